@@ -3,12 +3,16 @@ package api
 import (
 	"database/sql"
 	"log"
+	"slices"
 
 	"github.com/froggy-12/mooshroombase_v2/configs"
 	"github.com/froggy-12/mooshroombase_v2/middlewares"
 	"github.com/froggy-12/mooshroombase_v2/routes"
-	mongoauth "github.com/froggy-12/mooshroombase_v2/services/authentication/mongo_auth"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/apikeys"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/auth"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
 	servefiles "github.com/froggy-12/mooshroombase_v2/services/serve_files"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -17,18 +21,22 @@ import (
 )
 
 type Server struct {
-	addr          string
-	mongoClient   *mongo.Client
-	redisClient   *redis.Client
-	mariaDBClient *sql.DB
+	addr           string
+	mongoClient    *mongo.Client
+	redisClient    *redis.Client
+	mariaDBClient  *sql.DB
+	postgresClient *sql.DB
+	sqliteClient   *sql.DB
 }
 
-func NewAPIServer(addr string, mongClient *mongo.Client, redisClient *redis.Client, mariaDBClient *sql.DB) *Server {
+func NewAPIServer(addr string, mongClient *mongo.Client, redisClient *redis.Client, mariaDBClient *sql.DB, postgresClient *sql.DB, sqliteClient *sql.DB) *Server {
 	return &Server{
-		addr:          addr,
-		mongoClient:   mongClient,
-		redisClient:   redisClient,
-		mariaDBClient: mariaDBClient,
+		addr:           addr,
+		mongoClient:    mongClient,
+		redisClient:    redisClient,
+		mariaDBClient:  mariaDBClient,
+		postgresClient: postgresClient,
+		sqliteClient:   sqliteClient,
 	}
 }
 
@@ -45,6 +53,11 @@ func (s *Server) Start() error {
 		app.Use(logger.New())
 	}
 
+	var users store.UserStore
+	if configs.Configs.Authentication.Auth {
+		users = s.userStore()
+	}
+
 	if configs.Configs.ExtraConfigurations.RealTimeMainSwitch {
 		app.Use("/ws", func(c *fiber.Ctx) error {
 			if websocket.IsWebSocketUpgrade(c) {
@@ -53,12 +66,10 @@ func (s *Server) Start() error {
 			}
 			return fiber.ErrUpgradeRequired
 		})
-		if configs.Configs.DatabaseConfigurations.PrimaryDB == "mongodb" {
-			if configs.Configs.Authentication.RealTimeUserData {
-				app.Use("/ws/api/user/get-user", websocket.New(func(c *websocket.Conn) {
-					mongoauth.GetRealTimeUserData(c, s.mongoClient)
-				}))
-			}
+		if configs.Configs.Authentication.RealTimeUserData && users != nil {
+			app.Use("/ws/api/user/get-user", middlewares.CheckAndRefreshJWTTokenMiddleware, middlewares.RequireAPIKeyScope("data"), websocket.New(func(c *websocket.Conn) {
+				auth.GetRealTimeUserData(c, users)
+			}))
 		}
 	}
 
@@ -69,13 +80,19 @@ func (s *Server) Start() error {
 	if configs.Configs.SMTPConfigurations.SMTPAllowedForEveryone {
 		app.Post("/api/email/send-email", routes.SendEmail)
 	} else {
-		router := app.Group("/api/email", middlewares.CheckAndRefreshJWTTokenMiddleware)
+		router := app.Group("/api/email", middlewares.CheckAndRefreshJWTTokenMiddleware, middlewares.RequirePermission("email:send"))
 		router.Post("/send-email", routes.SendEmail)
 	}
 
 	// routes
 	routes.FreeRoutes(freeRouter)
+	app.Get("/.well-known/jwks.json", routes.JWKS)
 	if configs.Configs.Features.FileUplaod {
+		// the guards are registered on the paths because groups with the same prefix share their middlewares
+		if configs.Configs.Authentication.Auth {
+			fileUploadingrouter.Use("/upload", middlewares.CheckAndRefreshJWTTokenMiddleware, middlewares.RequirePermission("files:upload"))
+			fileUploadingrouter.Use("/deletefile", middlewares.CheckAndRefreshJWTTokenMiddleware, middlewares.RequirePermission("files:delete"))
+		}
 		routes.FileUploadingRoutes(fileUploadingrouter)
 	}
 
@@ -85,49 +102,64 @@ func (s *Server) Start() error {
 		freeRouter.Get("/download_file", servefiles.DownloadFile)
 	}
 
-	// mongodb auth routes
-	if configs.Configs.Authentication.Auth {
-		if configs.Configs.DatabaseConfigurations.PrimaryDB == "mongodb" {
-			found := false
-			for _, db := range configs.Configs.DatabaseConfigurations.RunningDatabases {
-				if db == "mongodb" {
-					found = true
-					break
-				}
-			}
-			if found {
-				// mongo auth routes
-				authRouter := app.Group("/api/auth")
-				userRouter := app.Group("/api/data", middlewares.CheckAndRefreshJWTTokenMiddleware)
-				routes.MongoAuthRoutes(authRouter, s.mongoClient)
-				routes.UserRoutes(userRouter, s.mongoClient)
-				app.Get("/api/auth/user-id", middlewares.CheckAndRefreshJWTTokenMiddleware, routes.GetUserID)
-			} else {
-				log.Fatal("primary database set to mongodb but its not even running")
-			}
+	// auth routes, every primary database is used through the same user store
+	if users != nil {
+		adminRouter := app.Group("/api/admin", middlewares.CheckAndRefreshJWTTokenMiddleware, middlewares.RequireRole("admin"))
+		adminRouter.Post("/users/:id/unlock", routes.UnlockUser)
+		adminRouter.Post("/signing-keys/rotate", routes.RotateSigningKey)
+
+		sessions.UserRoles = users.FindRoles
+		sessions.UserDisabled = users.IsDisabled
+		apikeys.FindByHash = users.FindAPIKeyByHash
+		apikeys.MarkUsed = users.MarkAPIKeyUsed
+
+		authRouter := app.Group("/api/auth")
+		userRouter := app.Group("/api/data", middlewares.CheckAndRefreshJWTTokenMiddleware, middlewares.RequireAPIKeyScope("data"))
+		routes.AuthRoutes(authRouter, users)
+		routes.AdminRoutes(adminRouter, users)
+		routes.UserRoutes(userRouter, users)
+		if configs.Configs.Authentication.OAuth {
+			routes.OAuthRoutes(authRouter.Group("/oauth"), users)
+			routes.OAuthLinkRoutes(userRouter.Group("/oauth"), users)
 		}
-
-		if configs.Configs.DatabaseConfigurations.PrimaryDB == "mariadb" {
-			found := false
-			for _, db := range configs.Configs.DatabaseConfigurations.RunningDatabases {
-				if db == "mariadb" {
-					found = true
-					break
-				}
-			}
-
-			if found {
-				authRouter := app.Group("/api/auth")
-				routes.MariaDBAuthRoutes(authRouter, s.mariaDBClient)
-				userRouter := app.Group("/api/data", middlewares.CheckAndRefreshJWTTokenMiddleware)
-				routes.MariaUserRoutes(userRouter, s.mariaDBClient)
-				app.Get("/api/auth/user-id", middlewares.CheckAndRefreshJWTTokenMiddleware, routes.GetUserID)
-			} else {
-				log.Fatal("primary database set to mariadb but its not even running")
-			}
+		if configs.Configs.Authentication.Passkeys {
+			routes.PasskeyRoutes(authRouter.Group("/passkeys"), users)
+			routes.PasskeyUserRoutes(userRouter.Group("/passkeys"), users)
 		}
-
+		if configs.Configs.Authentication.MagicLink {
+			routes.MagicLinkRoutes(authRouter.Group("/magic-link"), users)
+		}
+		if configs.Configs.Authentication.GuestAccounts {
+			routes.GuestRoutes(authRouter, userRouter, users)
+			go auth.CleanUpExpiredGuests(users)
+		}
+		app.Get("/api/auth/user-id", middlewares.CheckAndRefreshJWTTokenMiddleware, routes.GetUserID)
+		app.Post("/api/auth/refresh", routes.RefreshTokens)
+		app.Post("/api/auth/log-out", routes.LogOut)
+		userRouter.Post("/log-out-everywhere", routes.LogOutEverywhere)
+		userRouter.Get("/sessions", routes.ListSessions)
+		userRouter.Delete("/sessions/:id", routes.RevokeSession)
 	}
 
 	return app.Listen(s.addr)
 }
+
+// the user store of the primary database, it has to be one of the running databases
+func (s *Server) userStore() store.UserStore {
+	primaryDB := configs.Configs.DatabaseConfigurations.PrimaryDB
+	if !slices.Contains(configs.Configs.DatabaseConfigurations.RunningDatabases, primaryDB) {
+		log.Fatal("primary database set to " + primaryDB + " but its not even running")
+	}
+
+	switch primaryDB {
+	case "mongodb":
+		return store.NewMongoStore(s.mongoClient)
+	case "mariadb":
+		return store.NewMariaStore(s.mariaDBClient)
+	case "postgres":
+		return store.NewPostgresStore(s.postgresClient)
+	case "sqlite":
+		return store.NewSQLiteStore(s.sqliteClient)
+	}
+	return nil
+}
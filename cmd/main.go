@@ -4,24 +4,33 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"os"
 	"time"
 
 	"github.com/froggy-12/mooshroombase_v2/api"
 	"github.com/froggy-12/mooshroombase_v2/configs"
 	"github.com/froggy-12/mooshroombase_v2/db"
 	"github.com/froggy-12/mooshroombase_v2/docker"
+	"github.com/froggy-12/mooshroombase_v2/jwtkeys"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
 	"github.com/froggy-12/mooshroombase_v2/utils"
 	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 var (
-	mongoClient   *mongo.Client
-	mariaDBClient *sql.DB
-	redisClient   *redis.Client
+	mongoClient    *mongo.Client
+	mariaDBClient  *sql.DB
+	postgresClient *sql.DB
+	sqliteClient   *sql.DB
+	redisClient    *redis.Client
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migrateCommand(os.Args[2:])
+		return
+	}
 
 	fmt.Println(`                                                                                          
                                      .:=+********+=-:                                     
@@ -74,6 +83,11 @@ ______ ______ ______`)
 	configs.CheckIfFieldsAreEmpty(configs.Configs)
 	fmt.Println("Configurations Done Starting the app.....😊")
 
+	// loading the jwt signing keys
+	if err := jwtkeys.Init(); err != nil {
+		log.Fatal("Failed to load the jwt signing keys: " + err.Error())
+	}
+
 	utils.DebugLogging = configs.Configs.ExtraConfigurations.DebugLogging
 	if !configs.Configs.ExtraConfigurations.DebugLogging {
 		fmt.Println("Starting....")
@@ -81,10 +95,41 @@ ______ ______ ______`)
 
 	// initializing docker
 	utils.DebugLogger("main", "Configurations Done Starting the app.....😊")
-	docker.Init()
-	utils.DebugLogger("main", "Docker initialization completed")
+	if docker.Required() {
+		docker.Init()
+		utils.DebugLogger("main", "Docker initialization completed")
+	} else {
+		utils.DebugLogger("main", "no containerized database configured skipping docker 🐋")
+	}
 
 	// initializing database connections
+	connectDatabases()
+	// only sqlite setups are allowed to run auth without redis, everything else either has redis or doesnt need sessions
+	if redisClient == nil && configs.Configs.DatabaseConfigurations.PrimaryDB == "sqlite" && configs.Configs.Authentication.Auth {
+		log.Println("Redis is not running, sessions are kept in the sqlite database which only works for a single instance")
+		storage, err := sessions.NewSQLiteStorage(sqliteClient)
+		if err != nil {
+			log.Fatal("Failed to prepare the session storage 🪶: " + err.Error())
+		}
+		sessions.Store = storage
+	}
+	utils.DebugLogger("main", "Database Connections are successfull 😊😊")
+
+	// initializing database configs
+	db.Init(mongoClient, redisClient, mariaDBClient, postgresClient, sqliteClient)
+
+	// Starting The API Server
+	utils.DebugLogger("main", "Starting The API Server 🎉🎉🎉🍾💥")
+	server := api.NewAPIServer(configs.Configs.Applications.BackEndPort, mongoClient, redisClient, mariaDBClient, postgresClient, sqliteClient)
+	err := server.Start()
+	if err != nil {
+		log.Fatal("Failed to start API Server: " + err.Error())
+	}
+
+}
+
+// connects to every running database
+func connectDatabases() {
 	utils.DebugLogger("main", "Starting Database initialization")
 	for _, database := range configs.Configs.DatabaseConfigurations.RunningDatabases {
 		switch database {
@@ -100,25 +145,22 @@ ______ ______ ______`)
 			utils.DebugLogger("main", "connecting to Redis 🔴")
 			redisURI := fmt.Sprintf("localhost:%v", configs.Configs.DatabaseConfigurations.RedisDBServerPort)
 			redisClient = db.ConnectToRedisDB(redisURI, configs.Configs.DatabaseConfigurations.RedisDBRootPassword)
+			sessions.Store = sessions.NewRedisStorage(redisClient)
 			utils.DebugLogger("main", "Connected to Redis 🔴🔴")
 		case "mariadb":
 			utils.DebugLogger("main", "connecting to MariaDB 🐬")
 			mariaDBURI := fmt.Sprintf("localhost:%v", configs.Configs.DatabaseConfigurations.MariaDBServerPort)
 			mariaDBClient = db.ConnectToMariaDB(configs.Configs.DatabaseConfigurations.MariaDBRootPassword, mariaDBURI)
 			utils.DebugLogger("main", "Connected to MariaDB 🐬🐬")
+		case "postgres":
+			utils.DebugLogger("main", "connecting to Postgres 🐘")
+			postgresURI := fmt.Sprintf("localhost:%v", configs.Configs.DatabaseConfigurations.PostgresServerPort)
+			postgresClient = db.ConnectToPostgres(configs.Configs.DatabaseConfigurations.PostgresRootPassword, postgresURI)
+			utils.DebugLogger("main", "Connected to Postgres 🐘🐘")
+		case "sqlite":
+			utils.DebugLogger("main", "opening SQLite 🪶")
+			sqliteClient = db.ConnectToSQLite(configs.Configs.DatabaseConfigurations.SQLitePath)
+			utils.DebugLogger("main", "Opened SQLite 🪶🪶")
 		}
 	}
-	utils.DebugLogger("main", "Database Connections are successfull 😊😊")
-
-	// initializing database configs
-	db.Init(mongoClient, redisClient, mariaDBClient)
-
-	// Starting The API Server
-	utils.DebugLogger("main", "Starting The API Server 🎉🎉🎉🍾💥")
-	server := api.NewAPIServer(configs.Configs.Applications.BackEndPort, mongoClient, redisClient, mariaDBClient)
-	err := server.Start()
-	if err != nil {
-		log.Fatal("Failed to start API Server: " + err.Error())
-	}
-
 }
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/db"
+	"github.com/froggy-12/mooshroombase_v2/utils"
+)
+
+const migrateUsage = `usage: mooshroombase migrate <command>
+  up          applies every pending migration
+  down [n]    reverts the last n applied migrations, by default 1
+  status      lists the migrations and when they were applied`
+
+// migrate up/down/status for the primary database, the databases have to be running already
+func migrateCommand(args []string) {
+	if len(args) == 0 {
+		fmt.Println(migrateUsage)
+		return
+	}
+
+	configs.Configs = configs.InitConfigs()
+	configs.CheckIfFieldsAreEmpty(configs.Configs)
+	utils.DebugLogging = configs.Configs.ExtraConfigurations.DebugLogging
+
+	connectDatabases()
+	migrator, err := db.NewMigrator(mongoClient, mariaDBClient, postgresClient, sqliteClient)
+	if err != nil {
+		log.Fatal("Failed to read the migrations: " + err.Error())
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Printf("applied %04d %s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("the database is up to date")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatal("the number of migrations to revert has to be a positive number")
+			}
+		}
+		reverted, err := migrator.Down(steps)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %04d %s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(reverted) == 0 {
+			fmt.Println("no applied migrations to revert")
+		}
+	case "status":
+		states, err := migrator.Status()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, state := range states {
+			appliedAt := "pending"
+			if state.AppliedAt != nil {
+				appliedAt = "applied " + state.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d %-30s %s\n", state.Version, state.Name, appliedAt)
+		}
+	default:
+		fmt.Println(migrateUsage)
+	}
+}
@@ -1,6 +1,10 @@
 package configs
 
-import "log"
+import (
+	"log"
+	"regexp"
+	"time"
+)
 
 func CheckIfFieldsAreEmpty(c Config) {
 	if c.Applications.BackEndURlWithDomain == "" {
@@ -21,6 +25,22 @@ func CheckIfFieldsAreEmpty(c Config) {
 	if c.Authentication.GithubOAuth && c.Authentication.GithubOAuthAppSecret == "" {
 		log.Fatal("GithubOAuthAppSecret is empty")
 	}
+	oidcProviderNames := []string{}
+	for _, provider := range c.OIDCProviders {
+		if provider.Name == "" {
+			log.Fatal("OIDC provider name is empty")
+		}
+		if contains(oidcProviderNames, provider.Name) {
+			log.Fatal("OIDC provider name is used more than once: " + provider.Name)
+		}
+		if provider.IssuerURL == "" {
+			log.Fatal("IssuerURL of OIDC provider " + provider.Name + " is empty")
+		}
+		if provider.ClientID == "" {
+			log.Fatal("ClientID of OIDC provider " + provider.Name + " is empty")
+		}
+		oidcProviderNames = append(oidcProviderNames, provider.Name)
+	}
 	if c.SMTPConfigurations.SMTPEnabled {
 		if c.SMTPConfigurations.SMTPServerAddress == "" {
 			log.Fatal("SMTPServerAddress is empty")
@@ -47,14 +67,60 @@ func CheckIfFieldsAreEmpty(c Config) {
 	if c.DatabaseConfigurations.MariaDBRootPassword == "" && contains(c.DatabaseConfigurations.RunningDatabases, "mariadb") {
 		log.Fatal("MariaDBRootPassword is empty")
 	}
+	if c.DatabaseConfigurations.PostgresRootPassword == "" && contains(c.DatabaseConfigurations.RunningDatabases, "postgres") {
+		log.Fatal("PostgresRootPassword is empty")
+	}
+	if c.DatabaseConfigurations.SQLitePath == "" && contains(c.DatabaseConfigurations.RunningDatabases, "sqlite") {
+		log.Fatal("SQLitePath is empty")
+	}
 	if c.DatabaseConfigurations.RedisDBRootPassword == "" && contains(c.DatabaseConfigurations.RunningDatabases, "redis") {
 		log.Fatal("RedisDBRootPassword is empty")
 	}
 	if c.Features.ChatFunctions && !contains(c.DatabaseConfigurations.RunningDatabases, "redis") {
 		log.Fatal("ChatFunctions is enabled but Redis is not present in RunningDatabases")
 	}
+	if c.Authentication.MagicLink && !c.SMTPConfigurations.SMTPEnabled {
+		log.Fatal("MagicLink is enabled but SMTP is not enabled")
+	}
+	// sqlite runs without any other service so its sessions are kept in the sqlite database
+	if c.Authentication.Auth && !contains(c.DatabaseConfigurations.RunningDatabases, "redis") && c.DatabaseConfigurations.PrimaryDB != "sqlite" {
+		log.Fatal("Auth is enabled but Redis is not present in RunningDatabases, it is required to store the sessions")
+	}
+	if c.HttpConfigurations.JWTSigningAlgorithm != "" && !contains([]string{"RS256", "EdDSA", "HS256"}, c.HttpConfigurations.JWTSigningAlgorithm) {
+		log.Fatal("JWTSigningAlgorithm has to be RS256, EdDSA or HS256")
+	}
+	if c.Authentication.PasswordHashingAlgorithm != "" && !contains([]string{"argon2id", "bcrypt"}, c.Authentication.PasswordHashingAlgorithm) {
+		log.Fatal("PasswordHashingAlgorithm has to be argon2id or bcrypt")
+	}
+	if c.Authentication.BcryptCost != 0 && (c.Authentication.BcryptCost < 4 || c.Authentication.BcryptCost > 31) {
+		log.Fatal("BcryptCost has to be between 4 and 31")
+	}
+	if c.Authentication.Argon2Parallelism > 255 {
+		log.Fatal("Argon2Parallelism has to be at most 255")
+	}
+	if c.HttpConfigurations.JWTLegacyTokensUntil != "" {
+		if _, err := time.Parse(time.RFC3339, c.HttpConfigurations.JWTLegacyTokensUntil); err != nil {
+			log.Fatal("JWTLegacyTokensUntil has to be an RFC3339 time like 2006-01-02T15:04:05Z")
+		}
+	}
+	profileFieldNames := []string{}
+	for _, field := range c.ProfileFields {
+		if !profileFieldName.MatchString(field.Name) {
+			log.Fatal("profile field name can only have letters, numbers and _: " + field.Name)
+		}
+		if contains(profileFieldNames, field.Name) {
+			log.Fatal("profile field name is used more than once: " + field.Name)
+		}
+		if !contains([]string{"string", "number", "integer", "boolean"}, field.Type) {
+			log.Fatal("type of profile field " + field.Name + " has to be string, number, integer or boolean")
+		}
+		profileFieldNames = append(profileFieldNames, field.Name)
+	}
 }
 
+// profile field names are used as keys in the mongodb documents so they cant have dots or dollars
+var profileFieldName = regexp.MustCompile(`^[A-Za-z0-9_]{1,100}$`)
+
 func contains(slice []string, val string) bool {
 	for _, item := range slice {
 		if item == val {
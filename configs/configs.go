@@ -7,36 +7,75 @@ type Applications struct {
 }
 
 type Authentication struct {
-	Auth                         bool   `json:"auth"`                        // by default true
-	OAuth                        bool   `json:"oauth"`                       // by default false
-	GoogleOAuth                  bool   `json:"google_oauth"`                // by default false (it will require OAuth to be true)
-	GoogleOAuthAppID             string `json:"google_oauth_app_id"`         // required if Google OAuth enabled
-	GoogleOAuthAppSecret         string `json:"google_oauth_app_secret"`     // required if Google OAuth enabled
-	GithubOAuth                  bool   `json:"github_oauth"`                // by default false (it will require OAuth to be true)
-	GithubOAuthAppID             string `json:"github_oauth_app_id"`         // required if Github OAuth enabled
-	GithubOAuthAppSecret         string `json:"github_oauth_app_secret"`     // required if Github OAuth enabled
-	EmailVerificationAllowed     bool   `json:"email_verification_allowed"`  // adds some latency to the server and by default false and its preference dont need to turn on you should learn more about this first
-	SetJWTTokenAfterSignUp       bool   `json:"set_jwt_token_after_sign_up"` // its false by default
-	RealTimeUserData             bool   `json:"real_time_user_data"`         // by default false turn true for real time user data works with only mongodb not mariadb
-	SendEmailAfterSignUpWithCode bool   `json:"send_email_after_sign_up_with_code"`
+	Auth                         bool                `json:"auth"`                        // by default true
+	OAuth                        bool                `json:"oauth"`                       // by default false
+	GoogleOAuth                  bool                `json:"google_oauth"`                // by default false (it will require OAuth to be true)
+	GoogleOAuthAppID             string              `json:"google_oauth_app_id"`         // required if Google OAuth enabled
+	GoogleOAuthAppSecret         string              `json:"google_oauth_app_secret"`     // required if Google OAuth enabled
+	GithubOAuth                  bool                `json:"github_oauth"`                // by default false (it will require OAuth to be true)
+	GithubOAuthAppID             string              `json:"github_oauth_app_id"`         // required if Github OAuth enabled
+	GithubOAuthAppSecret         string              `json:"github_oauth_app_secret"`     // required if Github OAuth enabled
+	EmailVerificationAllowed     bool                `json:"email_verification_allowed"`  // adds some latency to the server and by default false and its preference dont need to turn on you should learn more about this first
+	SetJWTTokenAfterSignUp       bool                `json:"set_jwt_token_after_sign_up"` // its false by default
+	RealTimeUserData             bool                `json:"real_time_user_data"`         // by default false turn true for real time user data works with only mongodb not mariadb or postgres
+	SendEmailAfterSignUpWithCode bool                `json:"send_email_after_sign_up_with_code"`
+	GoogleOAuthAuthURL           string              `json:"google_oauth_auth_url"`           // by default https://accounts.google.com/o/oauth2/v2/auth change it for testing against a fake oauth server
+	GoogleOAuthTokenURL          string              `json:"google_oauth_token_url"`          // by default https://oauth2.googleapis.com/token
+	GoogleOAuthUserInfoURL       string              `json:"google_oauth_user_info_url"`      // by default https://openidconnect.googleapis.com/v1/userinfo
+	GithubOAuthAuthURL           string              `json:"github_oauth_auth_url"`           // by default https://github.com/login/oauth/authorize change it for testing against a fake oauth server
+	GithubOAuthTokenURL          string              `json:"github_oauth_token_url"`          // by default https://github.com/login/oauth/access_token
+	GithubOAuthAPIURL            string              `json:"github_oauth_api_url"`            // by default https://api.github.com
+	OAuthRedirectURL             string              `json:"oauth_redirect_url"`              // frontend url where user will be redirected after oauth log in, if empty json response will be sent
+	PasswordResetTokenExpiration int                 `json:"password_reset_token_expiration"` // by default 30 (1 = 1 minute)
+	PasswordResetURL             string              `json:"password_reset_url"`              // frontend page for resetting the password, the token will be added as ?token= query if empty only the token will be emailed
+	TOTPIssuer                   string              `json:"totp_issuer"`                     // name shown in authenticator apps by default Mooshroombase
+	Passkeys                     bool                `json:"passkeys"`                        // by default false
+	PasskeyDisplayName           string              `json:"passkey_display_name"`            // relying party name shown by the browser by default Mooshroombase, the relying party id is the host of BackEndURlWithDomain
+	MagicLink                    bool                `json:"magic_link"`                      // by default false (it will require SMTP to be enabled)
+	MagicLinkTokenExpiration     int                 `json:"magic_link_token_expiration"`     // by default 15 (1 = 1 minute)
+	MagicLinkRedirectURL         string              `json:"magic_link_redirect_url"`         // frontend page which gets the token of a clicked link as ?token= and posts it to /api/auth/magic-link/verify, if empty a page with a log in button is shown
+	MagicLinkSignUp              bool                `json:"magic_link_sign_up"`              // by default false, creates an account for unknown emails
+	MaxFailedLogInAttempts       int                 `json:"max_failed_log_in_attempts"`      // by default 5, the account gets locked after that many wrong passwords
+	AccountLockDuration          int                 `json:"account_lock_duration"`           // by default 15 (1 = 1 minute)
+	AdminUserIDs                 []string            `json:"admin_user_ids"`                  // users which always have the admin role, useful to set up the first admin by default empty
+	DefaultRoles                 []string            `json:"default_roles"`                   // roles of users without any assigned role by default user
+	RolePermissions              map[string][]string `json:"role_permissions"`                // permissions of every role, * allows everything by default admin has *, user has files:upload and email:send and guest has files:upload
+	GuestAccounts                bool                `json:"guest_accounts"`                  // by default false, lets the frontend create anonymous users which can be upgraded to full accounts later
+	GuestAccountLifetime         int                 `json:"guest_account_lifetime"`          // by default 30 (1 = 1 day) guests who never upgrade are deleted after that
+	PasswordHashingAlgorithm     string              `json:"password_hashing_algorithm"`      // argon2id or bcrypt by default argon2id, older hashes are replaced when the user logs in
+	Argon2Memory                 int                 `json:"argon2_memory"`                   // by default 65536 (1 = 1 KiB)
+	Argon2Iterations             int                 `json:"argon2_iterations"`               // by default 3
+	Argon2Parallelism            int                 `json:"argon2_parallelism"`              // by default 2
+	BcryptCost                   int                 `json:"bcrypt_cost"`                     // by default 10 only used when the algorithm is bcrypt
 }
 
 type DatabaseConfigurations struct {
-	PrimaryDB           string   `json:"primary_db"`             // either mongodb or mariadb (authentication will be handled by primary db)
-	RunningDatabases    []string `json:"running_databases"`      // mongodb, mariadb, redis otherwise wont work
-	MongoDBRootPassword string   `json:"mongodb_root_password"`  // by default it will be mooshroombase for root user u can change it ofc
-	MongoDBServerPort   string   `json:"mongodb_server_port"`    // by default it will be 66441
-	MariaDBRootPassword string   `json:"mariadb_root_password"`  // by default it will be mooshroombase for root
-	MariaDBServerPort   string   `json:"mariadb_server_port"`    // by default it will be 6645
-	RedisDBRootPassword string   `json:"redis_db_root_password"` // by default it will be mooshroombase
-	RedisDBServerPort   string   `json:"redis_db_server_port"`   // by default it will be 6656
+	PrimaryDB            string   `json:"primary_db"`             // either mongodb, mariadb, postgres or sqlite (authentication will be handled by primary db)
+	RunningDatabases     []string `json:"running_databases"`      // mongodb, mariadb, postgres, sqlite, redis otherwise wont work, sqlite without redis keeps the sessions in memory
+	MongoDBRootPassword  string   `json:"mongodb_root_password"`  // by default it will be mooshroombase for root user u can change it ofc
+	MongoDBServerPort    string   `json:"mongodb_server_port"`    // by default it will be 66441
+	MariaDBRootPassword  string   `json:"mariadb_root_password"`  // by default it will be mooshroombase for root
+	MariaDBServerPort    string   `json:"mariadb_server_port"`    // by default it will be 6645
+	PostgresRootPassword string   `json:"postgres_root_password"` // by default it will be mooshroombase for the postgres user
+	PostgresServerPort   string   `json:"postgres_server_port"`   // by default it will be 6647
+	SQLitePath           string   `json:"sqlite_path"`            // by default ./mooshroombase.db sqlite runs inside the app so it needs no container
+	RedisDBRootPassword  string   `json:"redis_db_root_password"` // by default it will be mooshroombase
+	RedisDBServerPort    string   `json:"redis_db_server_port"`   // by default it will be 6656
 	// note if u dont pay attention and do everything default it gonna make problems for future so please configure everything at once that is best.
 }
 
 type HttpConfigurations struct {
-	JWTSecret              string `json:"jwt_secret"`                // by default it will be SuperSecretMooshroombase
-	CorsHeaderMaxAge       int    `json:"cors_header_max_age"`       // by default 7 (1 = 1 day)
-	JWTTokenExpirationTime int    `json:"jwt_token_expiration_time"` // by default 7 (1 = 1 day)
+	JWTSecret                 string `json:"jwt_secret"`                   // by default it will be SuperSecretMooshroombase
+	CorsHeaderMaxAge          int    `json:"cors_header_max_age"`          // by default 7 (1 = 1 day)
+	JWTTokenExpirationTime    int    `json:"jwt_token_expiration_time"`    // by default 7 (1 = 1 day) its the lifetime of the refresh tokens and sessions
+	AccessTokenExpirationTime int    `json:"access_token_expiration_time"` // by default 15 (1 = 1 minute) older configs without it also get 15
+	JWTSigningAlgorithm       string `json:"jwt_signing_algorithm"`        // RS256, EdDSA or HS256 by default RS256, older configs without it keep HS256 with JWTSecret
+	JWTKeysDirectory          string `json:"jwt_keys_directory"`           // where the private signing keys are stored as pem files by default ./keys, every instance has to use the same directory (a shared volume)
+	JWTActiveKeyID            string `json:"jwt_active_key_id"`            // kid of the key new tokens are signed with by default empty which means the newest key
+	JWTKeyRotationInterval    int    `json:"jwt_key_rotation_interval"`    // by default 30 (1 = 1 day) 0 turns rotation off, retired keys are deleted after twice the interval
+	JWTIssuer                 string `json:"jwt_issuer"`                   // iss claim of the access tokens by default mooshroombase, empty leaves it out and skips the check
+	JWTAudience               string `json:"jwt_audience"`                 // aud claim of the access tokens by default mooshroombase, empty leaves it out and skips the check
+	JWTLegacyTokensUntil      string `json:"jwt_legacy_tokens_until"`      // RFC3339 time until tokens with the old expr claim can be exchanged for a session at /api/auth/refresh by default empty which rejects them
 }
 
 type SMTPConfigurations struct {
@@ -62,6 +101,30 @@ type Features struct {
 	ChatFunctions bool `json:"chat_functions"` // by default true its its enabled and there is no redis in the running database slice it will throw error
 }
 
+type OIDCProvider struct {
+	Name         string           `json:"name"`          // used in routes like /api/auth/oauth/oidc/<name>/start so keep it url friendly
+	IssuerURL    string           `json:"issuer_url"`    // discovery document will be loaded from <issuer_url>/.well-known/openid-configuration
+	ClientID     string           `json:"client_id"`     // required
+	ClientSecret string           `json:"client_secret"` // can be empty for public clients, pkce is always used
+	Scopes       []string         `json:"scopes"`        // by default openid, email and profile
+	ClaimMapping OIDCClaimMapping `json:"claim_mapping"` // which claims are used for the user fields
+}
+
+type OIDCClaimMapping struct {
+	UserName       string `json:"username"`        // by default preferred_username
+	FirstName      string `json:"first_name"`      // by default given_name
+	LastName       string `json:"last_name"`       // by default family_name
+	Email          string `json:"email"`           // by default email
+	ProfilePicture string `json:"profile_picture"` // by default picture
+}
+
+// a typed field users can keep on their profile next to the raw data
+type ProfileField struct {
+	Name      string `json:"name"`       // key of the field in profileFields, only letters, numbers and _
+	Type      string `json:"type"`       // string, number, integer or boolean
+	MaxLength int    `json:"max_length"` // only used for strings, by default 0 which means no limit
+}
+
 type Config struct {
 	Applications           Applications           `json:"applications"`
 	Authentication         Authentication         `json:"authentication"`
@@ -70,6 +133,8 @@ type Config struct {
 	SMTPConfigurations     SMTPConfigurations     `json:"smtp_configurations"`
 	ExtraConfigurations    ExtraConfigurations    `json:"extra_configurations"`
 	Features               Features               `json:"features"`
+	OIDCProviders          []OIDCProvider         `json:"oidc_providers"` // generic openid connect providers like keycloak, authentik or azure ad (it will require OAuth to be true)
+	ProfileFields          []ProfileField         `json:"profile_fields"` // custom profile fields which are validated on update-user-info, by default none
 }
 
 var Configs Config
@@ -44,21 +44,63 @@ func CreateDefaultConfig(configs *Config) {
 			SetJWTTokenAfterSignUp:       false,
 			RealTimeUserData:             false,
 			SendEmailAfterSignUpWithCode: true,
+			GoogleOAuthAuthURL:           "https://accounts.google.com/o/oauth2/v2/auth",
+			GoogleOAuthTokenURL:          "https://oauth2.googleapis.com/token",
+			GoogleOAuthUserInfoURL:       "https://openidconnect.googleapis.com/v1/userinfo",
+			GithubOAuthAuthURL:           "https://github.com/login/oauth/authorize",
+			GithubOAuthTokenURL:          "https://github.com/login/oauth/access_token",
+			GithubOAuthAPIURL:            "https://api.github.com",
+			OAuthRedirectURL:             "",
+			PasswordResetTokenExpiration: 30,
+			PasswordResetURL:             "",
+			TOTPIssuer:                   "Mooshroombase",
+			Passkeys:                     false,
+			PasskeyDisplayName:           "Mooshroombase",
+			MagicLink:                    false,
+			MagicLinkTokenExpiration:     15,
+			MagicLinkRedirectURL:         "",
+			MagicLinkSignUp:              false,
+			MaxFailedLogInAttempts:       5,
+			AccountLockDuration:          15,
+			AdminUserIDs:                 []string{},
+			DefaultRoles:                 []string{"user"},
+			RolePermissions: map[string][]string{
+				"admin": {"*"},
+				"user":  {"files:upload", "email:send"},
+				"guest": {"files:upload"},
+			},
+			GuestAccounts:            false,
+			GuestAccountLifetime:     30,
+			PasswordHashingAlgorithm: "argon2id",
+			Argon2Memory:             64 * 1024,
+			Argon2Iterations:         3,
+			Argon2Parallelism:        2,
+			BcryptCost:               10,
 		},
 		DatabaseConfigurations: DatabaseConfigurations{
-			PrimaryDB:           "mongodb",
-			RunningDatabases:    []string{"mongodb", "redis", "mariadb"},
-			MongoDBRootPassword: "mooshroombase",
-			MongoDBServerPort:   "27018",
-			MariaDBRootPassword: "mooshroombase",
-			MariaDBServerPort:   "6645",
-			RedisDBRootPassword: "mooshroombase",
-			RedisDBServerPort:   "6656",
+			PrimaryDB:            "mongodb",
+			RunningDatabases:     []string{"mongodb", "redis", "mariadb"},
+			MongoDBRootPassword:  "mooshroombase",
+			MongoDBServerPort:    "27018",
+			MariaDBRootPassword:  "mooshroombase",
+			MariaDBServerPort:    "6645",
+			PostgresRootPassword: "mooshroombase",
+			PostgresServerPort:   "6647",
+			SQLitePath:           "./mooshroombase.db",
+			RedisDBRootPassword:  "mooshroombase",
+			RedisDBServerPort:    "6656",
 		},
 		HttpConfigurations: HttpConfigurations{
-			JWTSecret:              "SuperSecretMooshroombase",
-			CorsHeaderMaxAge:       7,
-			JWTTokenExpirationTime: 7,
+			JWTSecret:                 "SuperSecretMooshroombase",
+			CorsHeaderMaxAge:          7,
+			JWTTokenExpirationTime:    7,
+			AccessTokenExpirationTime: 15,
+			JWTSigningAlgorithm:       "RS256",
+			JWTKeysDirectory:          "./keys",
+			JWTKeyRotationInterval:    30,
+			JWTIssuer:                 "mooshroombase",
+			JWTAudience:               "mooshroombase",
+			JWTLegacyTokensUntil:      "",
 		},
 		SMTPConfigurations: SMTPConfigurations{
 			SMTPEnabled:            false,
@@ -79,6 +121,8 @@ func CreateDefaultConfig(configs *Config) {
 			ServeFile:     true,
 			ChatFunctions: true,
 		},
+		OIDCProviders: []OIDCProvider{},
+		ProfileFields: []ProfileField{},
 	}
 	data, err := json.MarshalIndent(*configs, "", "  ")
 	if err != nil {
@@ -4,11 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"log"
+	"net/url"
 
 	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
 	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	_ "modernc.org/sqlite"
 )
 
 func ConnectToMongoDB(mongoDBURI string) *mongo.Client {
@@ -58,3 +61,50 @@ func ConnectToMariaDB(password, address string) *sql.DB {
 
 	return db
 }
+
+func ConnectToPostgres(password, address string) *sql.DB {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword("postgres", password),
+		Host:     address,
+		Path:     "postgres",
+		RawQuery: "sslmode=disable",
+	}
+
+	connector, err := pq.NewConnector(dsn.String())
+
+	if err != nil {
+		log.Fatal("Failed to create postgres client instance: " + err.Error())
+	}
+
+	db := sql.OpenDB(connector)
+	err = db.Ping()
+	if err != nil {
+		log.Fatal("Error Connecting to Postgres 🐘: ", err.Error())
+	}
+
+	return db
+}
+
+// the database file is created when it doesnt exist yet
+func ConnectToSQLite(path string) *sql.DB {
+	dsn := url.Values{}
+	dsn.Add("_pragma", "foreign_keys(1)")
+	dsn.Add("_pragma", "journal_mode(WAL)")
+	dsn.Add("_pragma", "busy_timeout(5000)")
+	dsn.Set("_time_format", "sqlite")
+	dsn.Set("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+dsn.Encode())
+
+	if err != nil {
+		log.Fatal("Failed to create sqlite client instance: " + err.Error())
+	}
+
+	err = db.Ping()
+	if err != nil {
+		log.Fatal("Error Opening SQLite 🪶: ", err.Error())
+	}
+
+	return db
+}
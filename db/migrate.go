@@ -0,0 +1,383 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// numbered up and down files of every sql database, like 0002_add_column.up.sql and 0002_add_column.down.sql
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// a version of the schema of the primary database
+type Migration struct {
+	Version int
+	Name    string
+}
+
+// a migration and when it was applied, AppliedAt is nil for pending migrations
+type MigrationState struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// the primary database the migrations are applied to
+type migrationTarget interface {
+	migrations() []Migration
+	// waits until no other instance is migrating, the returned func releases the lock
+	lock(ctx context.Context) (func(), error)
+	applied(ctx context.Context) (map[int]time.Time, error)
+	// applies or reverts the migration and records it in schema_migrations
+	run(ctx context.Context, migration Migration, up bool) error
+}
+
+type Migrator struct {
+	target migrationTarget
+}
+
+// migrator of the primary database
+func NewMigrator(mongoClient *mongo.Client, mariaDBClient *sql.DB, postgresClient *sql.DB, sqliteClient *sql.DB) (*Migrator, error) {
+	switch configs.Configs.DatabaseConfigurations.PrimaryDB {
+	case "mongodb":
+		return &Migrator{target: &mongoMigrationTarget{database: mongoClient.Database("mooshroombase")}}, nil
+	case "mariadb":
+		return newSQLMigrator(mariaDBClient, "mariadb")
+	case "postgres":
+		return newSQLMigrator(postgresClient, "postgres")
+	case "sqlite":
+		return newSQLMigrator(sqliteClient, "sqlite")
+	}
+	return nil, errors.New("unknown primary database: " + configs.Configs.DatabaseConfigurations.PrimaryDB)
+}
+
+// applies every pending migration in order and returns the applied ones
+func (m *Migrator) Up() ([]Migration, error) {
+	ctx := context.Background()
+	unlock, err := m.target.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := m.target.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+	for _, migration := range m.target.migrations() {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := m.target.run(ctx, migration, true); err != nil {
+			return done, fmt.Errorf("migration %d %s failed: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// reverts the last applied migrations newest first and returns the reverted ones
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	ctx := context.Background()
+	unlock, err := m.target.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	applied, err := m.target.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := m.target.migrations()
+	done := []Migration{}
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		if _, ok := applied[migrations[i].Version]; !ok {
+			continue
+		}
+		if err := m.target.run(ctx, migrations[i], false); err != nil {
+			return done, fmt.Errorf("reverting migration %d %s failed: %w", migrations[i].Version, migrations[i].Name, err)
+		}
+		done = append(done, migrations[i])
+	}
+	return done, nil
+}
+
+func (m *Migrator) Status() ([]MigrationState, error) {
+	applied, err := m.target.applied(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	states := []MigrationState{}
+	for _, migration := range m.target.migrations() {
+		state := MigrationState{Migration: migration}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			state.AppliedAt = &appliedAt
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+type sqlMigration struct {
+	Migration
+	up, down string
+}
+
+type sqlMigrationTarget struct {
+	db       *sql.DB
+	dialect  string
+	versions []sqlMigration
+}
+
+func newSQLMigrator(client *sql.DB, dialect string) (*Migrator, error) {
+	versions, err := readSQLMigrations(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{target: &sqlMigrationTarget{db: client, dialect: dialect, versions: versions}}, nil
+}
+
+// reads the embedded files of the dialect, every version needs an up and a down file
+func readSQLMigrations(dialect string) ([]sqlMigration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*sqlMigration{}
+	for _, entry := range entries {
+		name, direction, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, errors.New("migration file without .up.sql or .down.sql: " + entry.Name())
+		}
+		number, title, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(number)
+		if err != nil {
+			return nil, errors.New("migration file without a version number: " + entry.Name())
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &sqlMigration{Migration: Migration{Version: version, Name: title}}
+			byVersion[version] = migration
+		}
+		if direction == "up" {
+			migration.up = string(content)
+		} else {
+			migration.down = string(content)
+		}
+	}
+
+	migrations := []sqlMigration{}
+	for _, migration := range byVersion {
+		if migration.up == "" || migration.down == "" {
+			return nil, fmt.Errorf("migration %d of %s needs an up and a down file", migration.Version, dialect)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// statements of a migration file end with a ; at the end of a line, mariadb cant run them all at once
+func sqlStatements(content string) []string {
+	statements := []string{}
+	current := []string{}
+	for _, line := range strings.Split(content, "\n") {
+		current = append(current, line)
+		if strings.HasSuffix(strings.TrimSpace(line), ";") {
+			if statement := strings.TrimSpace(strings.Join(current, "\n")); statement != ";" {
+				statements = append(statements, statement)
+			}
+			current = []string{}
+		}
+	}
+	if statement := strings.TrimSpace(strings.Join(current, "\n")); statement != "" {
+		statements = append(statements, statement)
+	}
+	return statements
+}
+
+func (t *sqlMigrationTarget) migrations() []Migration {
+	migrations := []Migration{}
+	for _, migration := range t.versions {
+		migrations = append(migrations, migration.Migration)
+	}
+	return migrations
+}
+
+// the table of the applied versions, sqlite tables have no schema
+func (t *sqlMigrationTarget) table() string {
+	if t.dialect == "sqlite" {
+		return "schema_migrations"
+	}
+	return "mooshroombase.schema_migrations"
+}
+
+// postgres numbers its placeholders
+func (t *sqlMigrationTarget) placeholder(n int) string {
+	if t.dialect == "postgres" {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// creates the database or schema and the schema_migrations table
+func (t *sqlMigrationTarget) prepare(ctx context.Context) error {
+	statements := []string{}
+	switch t.dialect {
+	case "mariadb":
+		statements = append(statements,
+			`CREATE DATABASE IF NOT EXISTS mooshroombase`,
+			`CREATE TABLE IF NOT EXISTS mooshroombase.schema_migrations (Version BIGINT NOT NULL, Name VARCHAR(255) NOT NULL, AppliedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (Version))`,
+		)
+	case "postgres":
+		statements = append(statements,
+			`CREATE SCHEMA IF NOT EXISTS mooshroombase`,
+			`CREATE TABLE IF NOT EXISTS mooshroombase.schema_migrations (Version BIGINT NOT NULL, Name VARCHAR(255) NOT NULL, AppliedAt TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (Version))`,
+		)
+	case "sqlite":
+		statements = append(statements,
+			`CREATE TABLE IF NOT EXISTS schema_migrations (Version INTEGER NOT NULL, Name TEXT NOT NULL, AppliedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (Version))`,
+		)
+	}
+
+	for _, statement := range statements {
+		if _, err := t.db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mariadb and postgres have locks which belong to a connection, sqlite migrations take the write lock of the file
+// in their transaction and check the version again so they dont need one
+func (t *sqlMigrationTarget) lock(ctx context.Context) (func(), error) {
+	if err := t.prepare(ctx); err != nil {
+		return nil, err
+	}
+
+	if t.dialect == "sqlite" {
+		return func() {}, nil
+	}
+
+	conn, err := t.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var unlockQuery string
+	if t.dialect == "mariadb" {
+		unlockQuery = `SELECT RELEASE_LOCK('mooshroombase_migrations')`
+		var locked sql.NullInt64
+		err = conn.QueryRowContext(ctx, `SELECT GET_LOCK('mooshroombase_migrations', 300)`).Scan(&locked)
+		if err == nil && locked.Int64 != 1 {
+			err = errors.New("timed out waiting for the migration lock")
+		}
+	} else {
+		unlockQuery = `SELECT pg_advisory_unlock(hashtext('mooshroombase_migrations'))`
+		_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock(hashtext('mooshroombase_migrations'))`)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return func() {
+		if _, err := conn.ExecContext(context.Background(), unlockQuery); err != nil {
+			log.Println("Failed to release the migration lock: " + err.Error())
+		}
+		conn.Close()
+	}, nil
+}
+
+func (t *sqlMigrationTarget) applied(ctx context.Context) (map[int]time.Time, error) {
+	if err := t.prepare(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := t.db.QueryContext(ctx, `SELECT Version, AppliedAt FROM `+t.table())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// mariadb commits schema changes right away so only postgres and sqlite can roll back a failed migration
+func (t *sqlMigrationTarget) run(ctx context.Context, migration Migration, up bool) error {
+	var content string
+	for _, version := range t.versions {
+		if version.Version == migration.Version {
+			content = version.down
+			if up {
+				content = version.up
+			}
+		}
+	}
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+t.table()+` WHERE Version = `+t.placeholder(1), migration.Version).Scan(&count); err != nil {
+		return err
+	}
+	if (count > 0) == up {
+		// another instance got to it first
+		return tx.Commit()
+	}
+
+	for _, statement := range sqlStatements(content) {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, `INSERT INTO `+t.table()+` (Version, Name, AppliedAt) VALUES (`+t.placeholder(1)+`, `+t.placeholder(2)+`, `+t.placeholder(3)+`)`, migration.Version, migration.Name, time.Now())
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM `+t.table()+` WHERE Version = `+t.placeholder(1), migration.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
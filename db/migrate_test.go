@@ -0,0 +1,166 @@
+package db
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSQLStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"one statement", "CREATE TABLE a (ID INT);\n", []string{"CREATE TABLE a (ID INT);"}},
+		{"statement over several lines", "CREATE TABLE a (\n  ID INT\n);\n", []string{"CREATE TABLE a (\n  ID INT\n);"}},
+		{"several statements", "DROP TABLE a;\nDROP TABLE b;\n", []string{"DROP TABLE a;", "DROP TABLE b;"}},
+		{"last statement without a ;", "DROP TABLE a;\nDROP TABLE b", []string{"DROP TABLE a;", "DROP TABLE b"}},
+		{"empty lines between statements", "DROP TABLE a;\n\n\nDROP TABLE b;\n\n", []string{"DROP TABLE a;", "DROP TABLE b;"}},
+		{"; inside a line", "INSERT INTO a VALUES ('x;y');\n", []string{"INSERT INTO a VALUES ('x;y');"}},
+		{"empty file", "\n", []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := sqlStatements(test.content); !slices.Equal(got, test.want) {
+				t.Errorf("sqlStatements(%q) = %q, want %q", test.content, got, test.want)
+			}
+		})
+	}
+}
+
+// every dialect has the same versions so switching the primary database doesnt skip one
+func TestSQLMigrationsOfEveryDialect(t *testing.T) {
+	var versions []Migration
+	for _, dialect := range []string{"mariadb", "postgres", "sqlite"} {
+		migrations, err := readSQLMigrations(dialect)
+		if err != nil {
+			t.Fatalf("%s: %v", dialect, err)
+		}
+		found := []Migration{}
+		for _, migration := range migrations {
+			found = append(found, migration.Migration)
+		}
+		if versions == nil {
+			versions = found
+		} else if !slices.Equal(found, versions) {
+			t.Errorf("%s has the migrations %v, want %v", dialect, found, versions)
+		}
+	}
+}
+
+func newSQLiteMigrator(t *testing.T) *Migrator {
+	t.Helper()
+	client := ConnectToSQLite(filepath.Join(t.TempDir(), "mooshroombase.db"))
+	t.Cleanup(func() { client.Close() })
+	migrator, err := newSQLMigrator(client, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return migrator
+}
+
+func tableExists(t *testing.T, migrator *Migrator, table string) bool {
+	t.Helper()
+	var count int
+	err := migrator.target.(*sqlMigrationTarget).db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count > 0
+}
+
+func TestSQLiteUpAndDown(t *testing.T) {
+	migrator := newSQLiteMigrator(t)
+	all := migrator.target.migrations()
+
+	applied, err := migrator.Up()
+	if err != nil || !slices.Equal(applied, all) {
+		t.Fatalf("Up() = %v, %v, want every migration applied", applied, err)
+	}
+	for _, table := range []string{"users", "oauth_accounts", "passkeys", "api_keys"} {
+		if !tableExists(t, migrator, table) {
+			t.Errorf("table %s doesnt exist after Up()", table)
+		}
+	}
+
+	if again, err := migrator.Up(); err != nil || len(again) != 0 {
+		t.Errorf("second Up() = %v, %v, want nothing to do", again, err)
+	}
+
+	states, err := migrator.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, state := range states {
+		if state.AppliedAt == nil {
+			t.Errorf("migration %d is pending after Up()", state.Version)
+		}
+	}
+
+	reverted, err := migrator.Down(1)
+	if err != nil || len(reverted) != 1 || reverted[0] != all[len(all)-1] {
+		t.Fatalf("Down(1) = %v, %v, want the last migration reverted", reverted, err)
+	}
+	states, err = migrator.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := states[len(states)-1]; last.AppliedAt != nil {
+		t.Errorf("migration %d is still applied after Down(1)", last.Version)
+	}
+
+	if applied, err := migrator.Up(); err != nil || len(applied) != 1 {
+		t.Errorf("Up() after Down(1) = %v, %v, want the reverted migration applied again", applied, err)
+	}
+}
+
+// databases from before migrations already have the users table with the accounts, reverting every migration keeps it
+func TestSQLiteDownKeepsTheBaselineUsers(t *testing.T) {
+	migrator := newSQLiteMigrator(t)
+	client := migrator.target.(*sqlMigrationTarget).db
+	_, err := client.Exec(`CREATE TABLE users (
+  ID TEXT NOT NULL,
+  UserName TEXT NOT NULL UNIQUE,
+  FirstName TEXT NOT NULL,
+  LastName TEXT NOT NULL,
+  Email TEXT NOT NULL UNIQUE,
+  Password TEXT NOT NULL,
+  ProfilePicture TEXT,
+  CreatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UpdatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  Verified BOOLEAN NOT NULL DEFAULT FALSE,
+  VerificationToken TEXT,
+  LastLoggedIn TIMESTAMP,
+  PRIMARY KEY (ID)
+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Exec(`INSERT INTO users (ID, UserName, FirstName, LastName, Email, Password) VALUES ('user', 'frog', 'Frog', 'Gy', 'frog@example.com', 'hash')`); err != nil {
+		t.Fatal(err)
+	}
+
+	all := migrator.target.migrations()
+	if applied, err := migrator.Up(); err != nil || len(applied) != len(all) {
+		t.Fatalf("Up() = %v, %v, want every migration applied", applied, err)
+	}
+	if reverted, err := migrator.Down(len(all)); err != nil || len(reverted) != len(all) {
+		t.Fatalf("Down(%d) = %v, %v, want every migration reverted", len(all), reverted, err)
+	}
+
+	var count int
+	if err := client.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil || count != 1 {
+		t.Fatalf("users after Down() = %d, %v, want the account kept", count, err)
+	}
+	for _, table := range []string{"oauth_accounts", "passkeys", "api_keys", "user_profile_fields"} {
+		if tableExists(t, migrator, table) {
+			t.Errorf("table %s still exists after reverting every migration", table)
+		}
+	}
+
+	if applied, err := migrator.Up(); err != nil || len(applied) != len(all) {
+		t.Errorf("Up() after Down() = %v, %v, want every migration applied again", applied, err)
+	}
+}
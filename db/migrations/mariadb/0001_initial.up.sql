@@ -0,0 +1,105 @@
+CREATE TABLE IF NOT EXISTS mooshroombase.users (
+  ID VARCHAR(255) NOT NULL,
+  UserName VARCHAR(255) NOT NULL UNIQUE,
+  FirstName VARCHAR(255) NOT NULL,
+  LastName VARCHAR(255) NOT NULL,
+  Email VARCHAR(255) NOT NULL UNIQUE,
+  Password VARCHAR(255) NOT NULL,
+  ProfilePicture VARCHAR(255),
+  CreatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UpdatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  Verified BOOLEAN NOT NULL DEFAULT FALSE,
+  VerificationToken VARCHAR(255),
+  LastLoggedIn TIMESTAMP,
+  PRIMARY KEY (ID)
+);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.oauth_accounts (
+  Provider VARCHAR(50) NOT NULL,
+  ProviderUserID VARCHAR(255) NOT NULL,
+  UserID VARCHAR(255) NOT NULL,
+  Email VARCHAR(255),
+  LinkedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (Provider, ProviderUserID),
+  FOREIGN KEY (UserID) REFERENCES mooshroombase.users(ID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.password_resets (
+  TokenHash CHAR(64) NOT NULL,
+  UserID VARCHAR(255) NOT NULL,
+  ExpiresAt DATETIME NOT NULL,
+  CreatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (TokenHash),
+  FOREIGN KEY (UserID) REFERENCES mooshroombase.users(ID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.two_factor (
+  UserID VARCHAR(255) NOT NULL,
+  Secret VARCHAR(255) NOT NULL,
+  Enabled BOOLEAN NOT NULL DEFAULT FALSE,
+  CreatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (UserID),
+  FOREIGN KEY (UserID) REFERENCES mooshroombase.users(ID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.recovery_codes (
+  CodeHash CHAR(64) NOT NULL,
+  UserID VARCHAR(255) NOT NULL,
+  PRIMARY KEY (UserID, CodeHash),
+  FOREIGN KEY (UserID) REFERENCES mooshroombase.users(ID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.passkeys (
+  ID VARCHAR(255) NOT NULL,
+  UserID VARCHAR(255) NOT NULL,
+  Name VARCHAR(255) NOT NULL,
+  PublicKey BLOB NOT NULL,
+  AttestationType VARCHAR(255) NOT NULL,
+  Transports VARCHAR(255) NOT NULL,
+  SignCount INT UNSIGNED NOT NULL DEFAULT 0,
+  AAGUID VARBINARY(16) NOT NULL,
+  BackupEligible BOOLEAN NOT NULL DEFAULT FALSE,
+  BackupState BOOLEAN NOT NULL DEFAULT FALSE,
+  CreatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  LastUsedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (ID),
+  INDEX (UserID),
+  FOREIGN KEY (UserID) REFERENCES mooshroombase.users(ID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.user_roles (
+  UserID VARCHAR(255) NOT NULL,
+  Role VARCHAR(100) NOT NULL,
+  PRIMARY KEY (UserID, Role),
+  FOREIGN KEY (UserID) REFERENCES mooshroombase.users(ID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.disabled_users (
+  UserID VARCHAR(255) NOT NULL,
+  Reason VARCHAR(1000) NOT NULL DEFAULT '',
+  DisabledAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (UserID),
+  FOREIGN KEY (UserID) REFERENCES mooshroombase.users(ID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.guest_users (
+  UserID VARCHAR(255) NOT NULL,
+  CreatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (UserID),
+  INDEX (CreatedAt),
+  FOREIGN KEY (UserID) REFERENCES mooshroombase.users(ID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.api_keys (
+  ID VARCHAR(255) NOT NULL,
+  Name VARCHAR(255) NOT NULL,
+  KeyHash CHAR(64) NOT NULL UNIQUE,
+  Prefix VARCHAR(20) NOT NULL,
+  Scopes VARCHAR(1000) NOT NULL,
+  UserID VARCHAR(255),
+  CreatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  ExpiresAt DATETIME,
+  LastUsedAt DATETIME,
+  PRIMARY KEY (ID),
+  FOREIGN KEY (UserID) REFERENCES mooshroombase.users(ID) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS mooshroombase.user_profile_fields;
+DROP TABLE IF EXISTS mooshroombase.user_raw_data;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS mooshroombase.user_raw_data (
+  ID BIGINT NOT NULL AUTO_INCREMENT,
+  UserID VARCHAR(255) NOT NULL,
+  Data JSON NOT NULL,
+  PRIMARY KEY (ID),
+  INDEX (UserID),
+  FOREIGN KEY (UserID) REFERENCES mooshroombase.users(ID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.user_profile_fields (
+  UserID VARCHAR(255) NOT NULL,
+  Name VARCHAR(100) NOT NULL,
+  Value JSON NOT NULL,
+  PRIMARY KEY (UserID, Name),
+  FOREIGN KEY (UserID) REFERENCES mooshroombase.users(ID) ON DELETE CASCADE
+);
@@ -0,0 +1,10 @@
+-- users is the baseline table which existed before migrations and holds the accounts, reverting the baseline leaves it in place
+DROP TABLE IF EXISTS mooshroombase.api_keys;
+DROP TABLE IF EXISTS mooshroombase.guest_users;
+DROP TABLE IF EXISTS mooshroombase.disabled_users;
+DROP TABLE IF EXISTS mooshroombase.user_roles;
+DROP TABLE IF EXISTS mooshroombase.passkeys;
+DROP TABLE IF EXISTS mooshroombase.recovery_codes;
+DROP TABLE IF EXISTS mooshroombase.two_factor;
+DROP TABLE IF EXISTS mooshroombase.password_resets;
+DROP TABLE IF EXISTS mooshroombase.oauth_accounts;
@@ -0,0 +1,98 @@
+CREATE TABLE IF NOT EXISTS mooshroombase.users (
+  ID VARCHAR(255) NOT NULL,
+  UserName VARCHAR(255) NOT NULL UNIQUE,
+  FirstName VARCHAR(255) NOT NULL,
+  LastName VARCHAR(255) NOT NULL,
+  Email VARCHAR(255) NOT NULL UNIQUE,
+  Password VARCHAR(255) NOT NULL,
+  ProfilePicture VARCHAR(255),
+  CreatedAt TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UpdatedAt TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  Verified BOOLEAN NOT NULL DEFAULT FALSE,
+  VerificationToken VARCHAR(255),
+  LastLoggedIn TIMESTAMPTZ,
+  PRIMARY KEY (ID)
+);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.oauth_accounts (
+  Provider VARCHAR(50) NOT NULL,
+  ProviderUserID VARCHAR(255) NOT NULL,
+  UserID VARCHAR(255) NOT NULL REFERENCES mooshroombase.users(ID) ON DELETE CASCADE,
+  Email VARCHAR(255),
+  LinkedAt TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (Provider, ProviderUserID)
+);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.password_resets (
+  TokenHash CHAR(64) NOT NULL,
+  UserID VARCHAR(255) NOT NULL REFERENCES mooshroombase.users(ID) ON DELETE CASCADE,
+  ExpiresAt TIMESTAMPTZ NOT NULL,
+  CreatedAt TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (TokenHash)
+);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.two_factor (
+  UserID VARCHAR(255) NOT NULL REFERENCES mooshroombase.users(ID) ON DELETE CASCADE,
+  Secret VARCHAR(255) NOT NULL,
+  Enabled BOOLEAN NOT NULL DEFAULT FALSE,
+  CreatedAt TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (UserID)
+);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.recovery_codes (
+  CodeHash CHAR(64) NOT NULL,
+  UserID VARCHAR(255) NOT NULL REFERENCES mooshroombase.users(ID) ON DELETE CASCADE,
+  PRIMARY KEY (UserID, CodeHash)
+);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.passkeys (
+  ID VARCHAR(255) NOT NULL,
+  UserID VARCHAR(255) NOT NULL REFERENCES mooshroombase.users(ID) ON DELETE CASCADE,
+  Name VARCHAR(255) NOT NULL,
+  PublicKey BYTEA NOT NULL,
+  AttestationType VARCHAR(255) NOT NULL,
+  Transports VARCHAR(255) NOT NULL,
+  SignCount BIGINT NOT NULL DEFAULT 0,
+  AAGUID BYTEA NOT NULL,
+  BackupEligible BOOLEAN NOT NULL DEFAULT FALSE,
+  BackupState BOOLEAN NOT NULL DEFAULT FALSE,
+  CreatedAt TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  LastUsedAt TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (ID)
+);
+
+CREATE INDEX IF NOT EXISTS passkeys_userid ON mooshroombase.passkeys (UserID);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.user_roles (
+  UserID VARCHAR(255) NOT NULL REFERENCES mooshroombase.users(ID) ON DELETE CASCADE,
+  Role VARCHAR(100) NOT NULL,
+  PRIMARY KEY (UserID, Role)
+);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.disabled_users (
+  UserID VARCHAR(255) NOT NULL REFERENCES mooshroombase.users(ID) ON DELETE CASCADE,
+  Reason VARCHAR(1000) NOT NULL DEFAULT '',
+  DisabledAt TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (UserID)
+);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.guest_users (
+  UserID VARCHAR(255) NOT NULL REFERENCES mooshroombase.users(ID) ON DELETE CASCADE,
+  CreatedAt TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (UserID)
+);
+
+CREATE INDEX IF NOT EXISTS guest_users_createdat ON mooshroombase.guest_users (CreatedAt);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.api_keys (
+  ID VARCHAR(255) NOT NULL,
+  Name VARCHAR(255) NOT NULL,
+  KeyHash CHAR(64) NOT NULL UNIQUE,
+  Prefix VARCHAR(20) NOT NULL,
+  Scopes VARCHAR(1000) NOT NULL,
+  UserID VARCHAR(255) REFERENCES mooshroombase.users(ID) ON DELETE CASCADE,
+  CreatedAt TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  ExpiresAt TIMESTAMPTZ,
+  LastUsedAt TIMESTAMPTZ,
+  PRIMARY KEY (ID)
+);
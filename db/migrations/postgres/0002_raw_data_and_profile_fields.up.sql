@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS mooshroombase.user_raw_data (
+  ID BIGSERIAL NOT NULL,
+  UserID VARCHAR(255) NOT NULL REFERENCES mooshroombase.users(ID) ON DELETE CASCADE,
+  Data JSONB NOT NULL,
+  PRIMARY KEY (ID)
+);
+
+CREATE INDEX IF NOT EXISTS user_raw_data_userid ON mooshroombase.user_raw_data (UserID);
+
+CREATE TABLE IF NOT EXISTS mooshroombase.user_profile_fields (
+  UserID VARCHAR(255) NOT NULL REFERENCES mooshroombase.users(ID) ON DELETE CASCADE,
+  Name VARCHAR(100) NOT NULL,
+  Value JSONB NOT NULL,
+  PRIMARY KEY (UserID, Name)
+);
@@ -0,0 +1,10 @@
+-- users is the baseline table which existed before migrations and holds the accounts, reverting the baseline leaves it in place
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS guest_users;
+DROP TABLE IF EXISTS disabled_users;
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS passkeys;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS two_factor;
+DROP TABLE IF EXISTS password_resets;
+DROP TABLE IF EXISTS oauth_accounts;
@@ -0,0 +1,98 @@
+CREATE TABLE IF NOT EXISTS users (
+  ID TEXT NOT NULL,
+  UserName TEXT NOT NULL UNIQUE,
+  FirstName TEXT NOT NULL,
+  LastName TEXT NOT NULL,
+  Email TEXT NOT NULL UNIQUE,
+  Password TEXT NOT NULL,
+  ProfilePicture TEXT,
+  CreatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UpdatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  Verified BOOLEAN NOT NULL DEFAULT FALSE,
+  VerificationToken TEXT,
+  LastLoggedIn TIMESTAMP,
+  PRIMARY KEY (ID)
+);
+
+CREATE TABLE IF NOT EXISTS oauth_accounts (
+  Provider TEXT NOT NULL,
+  ProviderUserID TEXT NOT NULL,
+  UserID TEXT NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+  Email TEXT,
+  LinkedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (Provider, ProviderUserID)
+);
+
+CREATE TABLE IF NOT EXISTS password_resets (
+  TokenHash TEXT NOT NULL,
+  UserID TEXT NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+  ExpiresAt TIMESTAMP NOT NULL,
+  CreatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (TokenHash)
+);
+
+CREATE TABLE IF NOT EXISTS two_factor (
+  UserID TEXT NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+  Secret TEXT NOT NULL,
+  Enabled BOOLEAN NOT NULL DEFAULT FALSE,
+  CreatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (UserID)
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+  CodeHash TEXT NOT NULL,
+  UserID TEXT NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+  PRIMARY KEY (UserID, CodeHash)
+);
+
+CREATE TABLE IF NOT EXISTS passkeys (
+  ID TEXT NOT NULL,
+  UserID TEXT NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+  Name TEXT NOT NULL,
+  PublicKey BLOB NOT NULL,
+  AttestationType TEXT NOT NULL,
+  Transports TEXT NOT NULL,
+  SignCount INTEGER NOT NULL DEFAULT 0,
+  AAGUID BLOB NOT NULL,
+  BackupEligible BOOLEAN NOT NULL DEFAULT FALSE,
+  BackupState BOOLEAN NOT NULL DEFAULT FALSE,
+  CreatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  LastUsedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (ID)
+);
+
+CREATE INDEX IF NOT EXISTS passkeys_userid ON passkeys (UserID);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+  UserID TEXT NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+  Role TEXT NOT NULL,
+  PRIMARY KEY (UserID, Role)
+);
+
+CREATE TABLE IF NOT EXISTS disabled_users (
+  UserID TEXT NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+  Reason TEXT NOT NULL DEFAULT '',
+  DisabledAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (UserID)
+);
+
+CREATE TABLE IF NOT EXISTS guest_users (
+  UserID TEXT NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+  CreatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (UserID)
+);
+
+CREATE INDEX IF NOT EXISTS guest_users_createdat ON guest_users (CreatedAt);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+  ID TEXT NOT NULL,
+  Name TEXT NOT NULL,
+  KeyHash TEXT NOT NULL UNIQUE,
+  Prefix TEXT NOT NULL,
+  Scopes TEXT NOT NULL,
+  UserID TEXT REFERENCES users(ID) ON DELETE CASCADE,
+  CreatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  ExpiresAt TIMESTAMP,
+  LastUsedAt TIMESTAMP,
+  PRIMARY KEY (ID)
+);
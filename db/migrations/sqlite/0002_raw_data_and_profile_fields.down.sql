@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_profile_fields;
+DROP TABLE IF EXISTS user_raw_data;
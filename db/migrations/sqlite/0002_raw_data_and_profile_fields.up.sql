@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS user_raw_data (
+  ID INTEGER PRIMARY KEY AUTOINCREMENT,
+  UserID TEXT NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+  Data TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS user_raw_data_userid ON user_raw_data (UserID);
+
+CREATE TABLE IF NOT EXISTS user_profile_fields (
+  UserID TEXT NOT NULL REFERENCES users(ID) ON DELETE CASCADE,
+  Name TEXT NOT NULL,
+  Value TEXT NOT NULL,
+  PRIMARY KEY (UserID, Name)
+);
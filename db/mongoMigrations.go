@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongodb has no schema so its migrations are index changes written in go, new ones are added to the end
+var mongoMigrations = []mongoMigration{
+	{
+		Migration: Migration{Version: 1, Name: "initial"},
+		indexes: []mongoIndex{
+			{"users", mongo.IndexModel{Keys: bson.M{"email": 1}, Options: options.Index().SetName("email_1").SetUnique(true)}},
+			{"users", mongo.IndexModel{Keys: bson.M{"username": 1}, Options: options.Index().SetName("username_1").SetUnique(true)}},
+			{"users", mongo.IndexModel{
+				Keys:    bson.D{{Key: "oauthAccounts.provider", Value: 1}, {Key: "oauthAccounts.providerUserId", Value: 1}},
+				Options: options.Index().SetName("oauthAccounts.provider_1_oauthAccounts.providerUserId_1"),
+			}},
+			{"password_resets", mongo.IndexModel{Keys: bson.M{"tokenHash": 1}, Options: options.Index().SetName("tokenHash_1").SetUnique(true)}},
+			// expired reset tokens are removed by mongodb itself
+			{"password_resets", mongo.IndexModel{Keys: bson.M{"expiresAt": 1}, Options: options.Index().SetName("expiresAt_1").SetExpireAfterSeconds(0)}},
+			{"two_factor", mongo.IndexModel{Keys: bson.M{"userId": 1}, Options: options.Index().SetName("userId_1").SetUnique(true)}},
+			{"passkeys", mongo.IndexModel{Keys: bson.M{"id": 1}, Options: options.Index().SetName("id_1").SetUnique(true)}},
+			{"passkeys", mongo.IndexModel{Keys: bson.M{"userId": 1}, Options: options.Index().SetName("userId_1")}},
+			// guests are looked up by their creation time when the expired ones are cleaned up
+			{"users", mongo.IndexModel{
+				Keys:    bson.D{{Key: "guest", Value: 1}, {Key: "createdAt", Value: 1}},
+				Options: options.Index().SetName("guest_1_createdAt_1").SetPartialFilterExpression(bson.M{"guest": true}),
+			}},
+			{"api_keys", mongo.IndexModel{Keys: bson.M{"keyHash": 1}, Options: options.Index().SetName("keyHash_1").SetUnique(true)}},
+		},
+	},
+}
+
+// a migration creates its indexes and the down migration drops them again, the indexes need names for that
+type mongoIndex struct {
+	collection string
+	model      mongo.IndexModel
+}
+
+type mongoMigration struct {
+	Migration
+	indexes []mongoIndex
+}
+
+// locks older than this are left over from a crashed instance
+const mongoMigrationLockTimeout = 10 * time.Minute
+
+type mongoMigrationTarget struct {
+	database *mongo.Database
+}
+
+func (t *mongoMigrationTarget) migrations() []Migration {
+	migrations := []Migration{}
+	for _, migration := range mongoMigrations {
+		migrations = append(migrations, migration.Migration)
+	}
+	return migrations
+}
+
+// the lock is a document which only one instance can insert
+func (t *mongoMigrationTarget) lock(ctx context.Context) (func(), error) {
+	locks := t.database.Collection("migration_lock")
+	deadline := time.Now().Add(5 * time.Minute)
+	for {
+		_, err := locks.InsertOne(ctx, bson.M{"_id": "migrations", "lockedAt": time.Now()})
+		if err == nil {
+			return func() {
+				locks.DeleteOne(context.Background(), bson.M{"_id": "migrations"})
+			}, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+
+		if _, err := locks.DeleteOne(ctx, bson.M{"_id": "migrations", "lockedAt": bson.M{"$lt": time.Now().Add(-mongoMigrationLockTimeout)}}); err != nil {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.New("timed out waiting for the migration lock")
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func (t *mongoMigrationTarget) applied(ctx context.Context) (map[int]time.Time, error) {
+	cursor, err := t.database.Collection("schema_migrations").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []struct {
+		Version   int       `bson:"_id"`
+		AppliedAt time.Time `bson:"appliedAt"`
+	}
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	applied := map[int]time.Time{}
+	for _, record := range records {
+		applied[record.Version] = record.AppliedAt
+	}
+	return applied, nil
+}
+
+func (t *mongoMigrationTarget) run(ctx context.Context, migration Migration, up bool) error {
+	var indexes []mongoIndex
+	for _, version := range mongoMigrations {
+		if version.Version == migration.Version {
+			indexes = version.indexes
+		}
+	}
+
+	versions := t.database.Collection("schema_migrations")
+	if !up {
+		for i := len(indexes) - 1; i >= 0; i-- {
+			_, err := t.database.Collection(indexes[i].collection).Indexes().DropOne(ctx, *indexes[i].model.Options.Name)
+			if err != nil && !isIndexNotFound(err) {
+				return err
+			}
+		}
+		_, err := versions.DeleteOne(ctx, bson.M{"_id": migration.Version})
+		return err
+	}
+
+	for _, index := range indexes {
+		if _, err := t.database.Collection(index.collection).Indexes().CreateOne(ctx, index.model); err != nil {
+			return err
+		}
+	}
+	_, err := versions.InsertOne(ctx, bson.M{"_id": migration.Version, "name": migration.Name, "appliedAt": time.Now()})
+	return err
+}
+
+// dropping an index which was already removed by hand or whose collection is gone isnt an error
+func isIndexNotFound(err error) bool {
+	var commandError mongo.CommandError
+	return errors.As(err, &commandError) && (commandError.Code == 27 || commandError.Code == 26)
+}
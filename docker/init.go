@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"slices"
 	"strings"
 	"time"
 
@@ -16,6 +17,19 @@ import (
 	"github.com/froggy-12/mooshroombase_v2/utils"
 )
 
+// databases which are run as docker containers, sqlite runs inside the app
+var containerDatabases = []string{"mongodb", "redis", "mariadb", "postgres"}
+
+// tells if any of the running databases needs docker
+func Required() bool {
+	for _, database := range configs.Configs.DatabaseConfigurations.RunningDatabases {
+		if slices.Contains(containerDatabases, database) {
+			return true
+		}
+	}
+	return false
+}
+
 func Init() {
 	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 
@@ -23,7 +37,20 @@ func Init() {
 		log.Fatal("Error Creating API client for docker package: ", err.Error())
 	}
 
-	requriedImages := []string{"redis:latest", "mongo:latest", "mariadb:latest"}
+	// only the images of the running databases are pulled
+	requriedImages := []string{}
+	for _, database := range configs.Configs.DatabaseConfigurations.RunningDatabases {
+		switch database {
+		case "mongodb":
+			requriedImages = append(requriedImages, "mongo:latest")
+		case "redis":
+			requriedImages = append(requriedImages, "redis:latest")
+		case "mariadb":
+			requriedImages = append(requriedImages, "mariadb:latest")
+		case "postgres":
+			requriedImages = append(requriedImages, "postgres:latest")
+		}
+	}
 	utils.DebugLogger("docker", "checking installed Images")
 	installedImages, err := cli.ImageList(context.Background(), image.ListOptions{All: true})
 
@@ -73,6 +100,8 @@ func Init() {
 			requiredContainers = append(requiredContainers, "mooshroombase-redis")
 		case "mariadb":
 			requiredContainers = append(requiredContainers, "mooshroombase-mariadb")
+		case "postgres":
+			requiredContainers = append(requiredContainers, "mooshroombase-postgres")
 		}
 	}
 
@@ -111,6 +140,8 @@ func Init() {
 				err = createAndStartRedisDBContainer(cli, "mooshroombase-redis", configs.Configs.DatabaseConfigurations.RedisDBServerPort, "redis:latest", configs.Configs.DatabaseConfigurations.RedisDBRootPassword)
 			case "mooshroombase-mariadb":
 				err = createAndStartMariaDBContainer(cli, "mooshroombase-mariadb", configs.Configs.DatabaseConfigurations.MariaDBServerPort, "mariadb:latest", configs.Configs.DatabaseConfigurations.MariaDBRootPassword)
+			case "mooshroombase-postgres":
+				err = createAndStartPostgresContainer(cli, "mooshroombase-postgres", configs.Configs.DatabaseConfigurations.PostgresServerPort, "postgres:latest", configs.Configs.DatabaseConfigurations.PostgresRootPassword)
 			}
 			if err != nil {
 				log.Fatal("Error creating and starting container: ", err.Error())
@@ -268,3 +299,34 @@ func createAndStartMariaDBContainer(cli *client.Client, name string, port string
 	ticker.Stop()
 	return err
 }
+
+func createAndStartPostgresContainer(cli *client.Client, name string, port string, image string, password string) error {
+	utils.DebugLogger("docker", "Creating and Starting Postgres server")
+
+	containerConfig := &container.Config{
+		Image: image,
+		ExposedPorts: nat.PortSet{
+			nat.Port("5432"): struct{}{},
+		},
+		Env: []string{
+			"POSTGRES_PASSWORD=" + password,
+		},
+	}
+	hostConfig := container.HostConfig{
+		PortBindings: map[nat.Port][]nat.PortBinding{
+			nat.Port("5432"): {
+				{
+					HostIP:   "0.0.0.0",
+					HostPort: port,
+				},
+			},
+		},
+	}
+	containerName := name
+	cont, err := cli.ContainerCreate(context.Background(), containerConfig, &hostConfig, nil, nil, containerName)
+	if err != nil {
+		return err
+	}
+	err = cli.ContainerStart(context.Background(), cont.ID, container.StartOptions{})
+	return err
+}
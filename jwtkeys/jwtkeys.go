@@ -0,0 +1,419 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// every key is a pem file in the keys directory named after its kid, kids start with the creation time so the newest sorts last
+// the directory is the only place keys are kept so instances behind a load balancer have to share it, every instance
+// reloads it regularly and when it sees an unknown kid, only the instance holding the rotation lock creates or deletes keys
+type signingKey struct {
+	id         string
+	method     jwt.SigningMethod
+	privateKey crypto.Signer
+	createdAt  time.Time
+}
+
+var (
+	mu       sync.RWMutex
+	keys     = map[string]signingKey{}
+	active   string
+	loadedAt time.Time
+)
+
+const (
+	// microseconds keep keys created right after each other in order
+	kidTimeFormat = "20060102T150405.000000Z"
+	// how often every instance reads the directory again to pick up keys created by the others
+	reloadInterval = time.Minute
+	// unknown kids reload the directory at most this often so made up kids cant keep every request on the disk
+	unknownKeyReloadInterval = 10 * time.Second
+	// a lock file older than this is left over from a crashed instance
+	rotationLockTimeout = 10 * time.Minute
+)
+
+func algorithm() string {
+	if configs.Configs.HttpConfigurations.JWTSigningAlgorithm == "" {
+		return "HS256"
+	}
+	return configs.Configs.HttpConfigurations.JWTSigningAlgorithm
+}
+
+func directory() string {
+	if configs.Configs.HttpConfigurations.JWTKeysDirectory == "" {
+		return "./keys"
+	}
+	return configs.Configs.HttpConfigurations.JWTKeysDirectory
+}
+
+func rotationInterval() time.Duration {
+	return time.Hour * 24 * time.Duration(configs.Configs.HttpConfigurations.JWTKeyRotationInterval)
+}
+
+// tokens are signed with the shared JWTSecret when the algorithm is HS256, there are no keys to publish then
+func Symmetric() bool {
+	return algorithm() == "HS256"
+}
+
+// loads the signing keys and creates the first one if there is none, old keys are rotated out when rotation is turned on
+func Init() error {
+	if Symmetric() {
+		return nil
+	}
+
+	if err := os.MkdirAll(directory(), 0700); err != nil {
+		return err
+	}
+	if err := load(); err != nil {
+		return err
+	}
+
+	if err := rotateIfDue(); err != nil {
+		return err
+	}
+
+	// another instance holding the lock is creating the first key
+	for attempt := 0; !hasActiveKey() && attempt < 30; attempt++ {
+		time.Sleep(time.Second)
+		if err := load(); err != nil {
+			return err
+		}
+	}
+	if !hasActiveKey() {
+		return errors.New("there is no signing key and another instance holds the rotation lock")
+	}
+
+	go reloadInBackground()
+	return nil
+}
+
+// the first key is created the same way so instances starting together dont each create one
+func rotateIfDue() error {
+	if !rotationDue() {
+		return nil
+	}
+
+	unlock, locked, err := lockRotation()
+	if err != nil || !locked {
+		return err
+	}
+	defer unlock()
+
+	// another instance might have rotated while this one waited
+	if err := load(); err != nil {
+		return err
+	}
+	if !rotationDue() {
+		return nil
+	}
+	_, err = rotate()
+	return err
+}
+
+func hasActiveKey() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, found := keys[active]
+	return found
+}
+
+func rotationDue() bool {
+	mu.RLock()
+	current, found := keys[active]
+	mu.RUnlock()
+	return !found || (rotationInterval() > 0 && time.Since(current.createdAt) > rotationInterval())
+}
+
+// the lock is a file which only one instance can create, it returns false when another instance holds it
+func lockRotation() (func(), bool, error) {
+	file := filepath.Join(directory(), "rotation.lock")
+	for attempt := 0; attempt < 2; attempt++ {
+		lock, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			lock.Close()
+			return func() { os.Remove(file) }, true, nil
+		}
+		if !os.IsExist(err) {
+			return nil, false, err
+		}
+
+		info, err := os.Stat(file)
+		if err != nil || time.Since(info.ModTime()) < rotationLockTimeout {
+			return nil, false, nil
+		}
+		os.Remove(file)
+	}
+	return nil, false, nil
+}
+
+func load() error {
+	files, err := filepath.Glob(filepath.Join(directory(), "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	loaded := map[string]signingKey{}
+	for _, file := range files {
+		key, err := readKey(file)
+		if err != nil {
+			return errors.New("failed to read signing key " + file + ": " + err.Error())
+		}
+		loaded[key.id] = key
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	keys = loaded
+	active = pickActive(loaded)
+	loadedAt = time.Now()
+	return nil
+}
+
+// the configured key or the newest one
+func pickActive(loaded map[string]signingKey) string {
+	if id := configs.Configs.HttpConfigurations.JWTActiveKeyID; id != "" {
+		if _, found := loaded[id]; found {
+			return id
+		}
+	}
+
+	ids := make([]string, 0, len(loaded))
+	for id := range loaded {
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return ""
+	}
+	sort.Strings(ids)
+	return ids[len(ids)-1]
+}
+
+func readKey(file string) (signingKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return signingKey{}, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return signingKey{}, errors.New("no pem block found")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	id := strings.TrimSuffix(filepath.Base(file), ".pem")
+	key := signingKey{id: id, createdAt: createdAt(id, file)}
+	switch privateKey := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.privateKey = jwt.SigningMethodRS256, privateKey
+	case ed25519.PrivateKey:
+		key.method, key.privateKey = jwt.SigningMethodEdDSA, privateKey
+	default:
+		return signingKey{}, errors.New("only rsa and ed25519 keys are supported")
+	}
+	return key, nil
+}
+
+func createdAt(id, file string) time.Time {
+	if created, err := time.Parse(kidTimeFormat, strings.SplitN(id, "-", 2)[0]); err == nil {
+		return created
+	}
+	if info, err := os.Stat(file); err == nil {
+		return info.ModTime()
+	}
+	return time.Now()
+}
+
+// creates a new key with the configured algorithm and makes it the active one, older keys keep verifying tokens
+// until they are older than twice the rotation interval, the other instances start signing with it after their next reload
+func Rotate() (string, error) {
+	if Symmetric() {
+		return "", errors.New("keys can only be rotated with RS256 or EdDSA signing")
+	}
+
+	unlock, locked, err := lockRotation()
+	if err != nil {
+		return "", err
+	}
+	if !locked {
+		return "", errors.New("the signing key is being rotated by another instance")
+	}
+	defer unlock()
+
+	return rotate()
+}
+
+func rotate() (string, error) {
+	var privateKey crypto.Signer
+	var err error
+	switch algorithm() {
+	case "RS256":
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "EdDSA":
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return "", errors.New("unsupported signing algorithm: " + algorithm())
+	}
+	if err != nil {
+		return "", err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	id := time.Now().UTC().Format(kidTimeFormat) + "-" + hex.EncodeToString(suffix)
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(directory(), id+".pem"), data, 0600); err != nil {
+		return "", err
+	}
+
+	if err := load(); err != nil {
+		return "", err
+	}
+
+	return id, retireOldKeys()
+}
+
+func retireOldKeys() error {
+	if rotationInterval() <= 0 {
+		return nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for id, key := range keys {
+		if id != active && time.Since(key.createdAt) > 2*rotationInterval() {
+			if err := os.Remove(filepath.Join(directory(), id+".pem")); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			delete(keys, id)
+		}
+	}
+	return nil
+}
+
+func reloadInBackground() {
+	for range time.Tick(reloadInterval) {
+		if err := load(); err != nil {
+			log.Println("failed to reload the jwt signing keys: " + err.Error())
+			continue
+		}
+		if err := rotateIfDue(); err != nil {
+			log.Println("failed to rotate the jwt signing key: " + err.Error())
+		}
+	}
+}
+
+// algorithms tokens may be signed with, which key goes with which algorithm is checked in Keyfunc
+func ValidMethods() []string {
+	if Symmetric() {
+		return []string{jwt.SigningMethodHS256.Alg()}
+	}
+	return []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+}
+
+// signs the claims with the active key, its id is set as the kid header
+func Sign(claims jwt.Claims) (string, error) {
+	if Symmetric() {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(configs.Configs.HttpConfigurations.JWTSecret))
+	}
+
+	mu.RLock()
+	key, found := keys[active]
+	mu.RUnlock()
+	if !found {
+		return "", errors.New("there is no active signing key")
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.privateKey)
+}
+
+// finds the key a token has been signed with, the algorithm of the token has to match the key
+func Keyfunc(token *jwt.Token) (interface{}, error) {
+	if Symmetric() {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(configs.Configs.HttpConfigurations.JWTSecret), nil
+	}
+
+	id, _ := token.Header["kid"].(string)
+	mu.RLock()
+	key, found := keys[id]
+	recentlyLoaded := time.Since(loadedAt) < unknownKeyReloadInterval
+	mu.RUnlock()
+	// the key might have been created by another instance since the last reload
+	if !found && !recentlyLoaded {
+		if err := load(); err != nil {
+			return nil, err
+		}
+		mu.RLock()
+		key, found = keys[id]
+		mu.RUnlock()
+	}
+	if !found {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.privateKey.Public(), nil
+}
+
+// the public keys as a json web key set
+func JWKS() map[string]any {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	ids := make([]string, 0, len(keys))
+	for id := range keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	set := []map[string]any{}
+	for _, id := range ids {
+		key := keys[id]
+		jwk := map[string]any{"kid": key.id, "use": "sig", "alg": key.method.Alg()}
+		switch publicKey := key.privateKey.Public().(type) {
+		case *rsa.PublicKey:
+			jwk["kty"] = "RSA"
+			jwk["n"] = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk["kty"] = "OKP"
+			jwk["crv"] = "Ed25519"
+			jwk["x"] = base64.RawURLEncoding.EncodeToString(publicKey)
+		}
+		set = append(set, jwk)
+	}
+	return map[string]any{"keys": set}
+}
@@ -0,0 +1,226 @@
+package jwtkeys
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// every test starts without loaded keys and with its own keys directory
+func useKeys(t *testing.T, algorithm string) string {
+	t.Helper()
+	previous := configs.Configs.HttpConfigurations
+	directory := t.TempDir()
+	configs.Configs.HttpConfigurations = configs.HttpConfigurations{
+		JWTSecret:           "secret",
+		JWTSigningAlgorithm: algorithm,
+		JWTKeysDirectory:    directory,
+	}
+	mu.Lock()
+	keys, active = map[string]signingKey{}, ""
+	mu.Unlock()
+	t.Cleanup(func() { configs.Configs.HttpConfigurations = previous })
+	return directory
+}
+
+func mustSign(t *testing.T) string {
+	t.Helper()
+	token, err := Sign(jwt.MapClaims{"sub": "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func verify(token string) error {
+	_, err := jwt.Parse(token, Keyfunc)
+	return err
+}
+
+func TestSignAndVerify(t *testing.T) {
+	for _, algorithm := range []string{"HS256", "RS256", "EdDSA"} {
+		t.Run(algorithm, func(t *testing.T) {
+			useKeys(t, algorithm)
+			if err := Init(); err != nil {
+				t.Fatal(err)
+			}
+			if err := verify(mustSign(t)); err != nil {
+				t.Errorf("token signed with %s doesnt verify: %v", algorithm, err)
+			}
+		})
+	}
+}
+
+func TestInitKeepsExistingKeys(t *testing.T) {
+	directory := useKeys(t, "EdDSA")
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	token := mustSign(t)
+
+	// like a restart of the server
+	mu.Lock()
+	keys, active = map[string]signingKey{}, ""
+	mu.Unlock()
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(directory, "*.pem"))
+	if len(files) != 1 {
+		t.Errorf("keys directory has %d keys after a restart, want 1", len(files))
+	}
+	if err := verify(token); err != nil {
+		t.Errorf("token signed before the restart doesnt verify: %v", err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	useKeys(t, "EdDSA")
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	before := mustSign(t)
+
+	id, err := Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := mustSign(t)
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(after, jwt.MapClaims{})
+	if err != nil || parsed.Header["kid"] != id {
+		t.Errorf("new tokens are signed with %v, want the rotated key %s", parsed.Header["kid"], id)
+	}
+	if err := verify(before); err != nil {
+		t.Errorf("token signed before the rotation doesnt verify: %v", err)
+	}
+	if err := verify(after); err != nil {
+		t.Errorf("token signed after the rotation doesnt verify: %v", err)
+	}
+	if published := JWKS()["keys"].([]map[string]any); len(published) != 2 {
+		t.Errorf("JWKS() has %d keys, want the old and the new one", len(published))
+	}
+}
+
+func TestRetireOldKeys(t *testing.T) {
+	directory := useKeys(t, "EdDSA")
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	old := mustSign(t)
+
+	// the first key pretends to be from two months ago
+	files, _ := filepath.Glob(filepath.Join(directory, "*.pem"))
+	retired := filepath.Join(directory, time.Now().AddDate(0, -2, 0).UTC().Format(kidTimeFormat)+"-00000000.pem")
+	if err := os.Rename(files[0], retired); err != nil {
+		t.Fatal(err)
+	}
+	configs.Configs.HttpConfigurations.JWTKeyRotationInterval = 10
+	if _, err := Rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(retired); !os.IsNotExist(err) {
+		t.Errorf("key older than twice the rotation interval still exists: %v", err)
+	}
+	if err := verify(old); err == nil {
+		t.Error("token of a retired key still verifies")
+	}
+}
+
+func TestKeyfuncRejects(t *testing.T) {
+	useKeys(t, "EdDSA")
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	valid := mustSign(t)
+
+	hs256, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "user"}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknownKid := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "user"})
+	unknownKid.Header["kid"] = "unknown"
+	unknown, err := unknownKid.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the kid of a real key with the algorithm switched to the one of the shared secret
+	parsed, _, _ := jwt.NewParser().ParseUnverified(valid, jwt.MapClaims{})
+	confused := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "user"})
+	confused.Header["kid"] = parsed.Header["kid"]
+	confusedToken, err := confused.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"shared secret while signing with keys", hs256},
+		{"unknown kid", unknown},
+		{"algorithm of the key switched", confusedToken},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := verify(test.token); err == nil {
+				t.Error("token was accepted")
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		want      map[string]any
+		fields    []string
+	}{
+		{"RS256", map[string]any{"kty": "RSA", "alg": "RS256", "use": "sig"}, []string{"n", "e"}},
+		{"EdDSA", map[string]any{"kty": "OKP", "alg": "EdDSA", "crv": "Ed25519", "use": "sig"}, []string{"x"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.algorithm, func(t *testing.T) {
+			useKeys(t, test.algorithm)
+			if err := Init(); err != nil {
+				t.Fatal(err)
+			}
+
+			published := JWKS()["keys"].([]map[string]any)
+			if len(published) != 1 {
+				t.Fatalf("JWKS() = %v, want one key", published)
+			}
+			jwk := published[0]
+			for field, value := range test.want {
+				if jwk[field] != value {
+					t.Errorf("%s = %v, want %v", field, jwk[field], value)
+				}
+			}
+			for _, field := range append(test.fields, "kid") {
+				if value, _ := jwk[field].(string); value == "" {
+					t.Errorf("%s is missing", field)
+				}
+			}
+			if _, found := jwk["d"]; found {
+				t.Error("the private key is published")
+			}
+		})
+	}
+
+	t.Run("HS256", func(t *testing.T) {
+		useKeys(t, "HS256")
+		if err := Init(); err != nil {
+			t.Fatal(err)
+		}
+		if published := JWKS()["keys"].([]map[string]any); len(published) != 0 {
+			t.Errorf("JWKS() = %v, the shared secret must never be published", published)
+		}
+	})
+}
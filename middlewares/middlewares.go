@@ -2,10 +2,13 @@ package middlewares
 
 import (
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/apikeys"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
 	"github.com/froggy-12/mooshroombase_v2/types"
 	"github.com/froggy-12/mooshroombase_v2/utils"
 	"github.com/gofiber/fiber/v2"
@@ -19,25 +22,125 @@ var CorsMiddleWare = cors.New(cors.Config{
 	MaxAge:       time.Now().Hour() * 24 * configs.Configs.HttpConfigurations.CorsHeaderMaxAge,
 })
 
+// the access token is read from the cookie or from Authorization: Bearer <jwt> for clients without cookies
+// access tokens are short lived and no longer refreshed here, clients get new ones from /api/auth/refresh
+// requests with an api key skip the token, what the key may do is checked by RequirePermission and RequireAPIKeyScope
 func CheckAndRefreshJWTTokenMiddleware(c *fiber.Ctx) error {
-	userId, expired, err := utils.ReadJWTToken(c.Cookies("jwtToken"), configs.Configs.HttpConfigurations.JWTSecret)
+	apiKey, sent, err := apikeys.Authenticate(c)
+	if sent {
+		return useAPIKey(c, apiKey, err)
+	}
+
+	claims, expired, err := utils.ParseAccessToken(utils.AccessToken(c))
 	if err != nil {
 		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "User is not authorised please log in"})
 	}
 	if expired {
-		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Please Log in"})
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "Access token has expired please refresh it using /api/auth/refresh"})
+	}
+	if claims.Legacy {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "Access token has the old format please exchange it for a session using /api/auth/refresh"})
+	}
+	if claims.SessionID == "" {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "User is not authorised please log in"})
+	}
+	// revoking all sessions of the user revokes this one too so its tokens are covered by the session check
+	revoked, err := sessions.IsTokenRevoked(claims.JTI)
+	if err == nil && !revoked {
+		revoked, err = sessions.IsSessionRevoked(claims.SessionID)
 	}
-	// Pass the token instead of the user ID
-	newToken, err := utils.RefreshJWTToken(c.Cookies("jwtToken"), configs.Configs.HttpConfigurations.JWTSecret, configs.Configs.HttpConfigurations.JWTTokenExpirationTime)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(types.ErrorResponse{
-			Error: "Failed to refresh JWT token",
-		})
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to check the session: " + err.Error()})
+	}
+	if revoked {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "Session has been revoked please log in again"})
 	}
 
-	utils.SetJwtHttpCookies(c, newToken, configs.Configs.HttpConfigurations.JWTTokenExpirationTime)
+	// the session can expire between the check and the update
+	err = sessions.Touch(claims.SessionID, c.IP())
+	if err == sessions.ErrSessionRevoked {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "Session has been revoked please log in again"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to update the session: " + err.Error()})
+	}
 
-	c.Locals("userId", userId)
+	c.Locals("userId", claims.UserID)
+	c.Locals("jti", claims.JTI)
+	c.Locals("sessionId", claims.SessionID)
+	c.Locals("roles", claims.Roles)
 
 	return c.Next()
 }
+
+func useAPIKey(c *fiber.Ctx, apiKey types.APIKey, err error) error {
+	if err == apikeys.ErrInvalidAPIKey || err == apikeys.ErrExpiredAPIKey {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to check the api key: " + err.Error()})
+	}
+
+	if apiKey.UserID != "" {
+		disabled, err := sessions.IsUserDisabled(apiKey.UserID)
+		if err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to check the api key: " + err.Error()})
+		}
+		if disabled {
+			return c.Status(http.StatusForbidden).JSON(types.ErrorResponse{Error: "User of the api key has been disabled"})
+		}
+		c.Locals("userId", apiKey.UserID)
+	}
+	c.Locals("apiKey", apiKey)
+
+	return c.Next()
+}
+
+// has to run after the jwt middleware, the user needs at least one of the roles
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userRoles, _ := c.Locals("roles").([]string)
+		for _, role := range roles {
+			if slices.Contains(userRoles, role) {
+				return c.Next()
+			}
+		}
+		return c.Status(http.StatusForbidden).JSON(types.ErrorResponse{Error: "User doesnt have the role to do this"})
+	}
+}
+
+// has to run after the jwt middleware, permissions of each role come from RolePermissions and api keys need the permission as a scope
+func RequirePermission(permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if apiKey, ok := c.Locals("apiKey").(types.APIKey); ok {
+			if !apikeys.HasScope(apiKey, permission) {
+				return c.Status(http.StatusForbidden).JSON(types.ErrorResponse{Error: "API key doesnt have the scope to do this: " + permission})
+			}
+			return c.Next()
+		}
+
+		userRoles, _ := c.Locals("roles").([]string)
+		if !sessions.HasPermission(userRoles, permission) {
+			return c.Status(http.StatusForbidden).JSON(types.ErrorResponse{Error: "User doesnt have the permission to do this: " + permission})
+		}
+		return c.Next()
+	}
+}
+
+// has to run after the jwt middleware, requests with an api key need the scope and the key has to belong to a user
+// requests with an access token are let through
+func RequireAPIKeyScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		apiKey, ok := c.Locals("apiKey").(types.APIKey)
+		if !ok {
+			return c.Next()
+		}
+		if !apikeys.HasScope(apiKey, scope) {
+			return c.Status(http.StatusForbidden).JSON(types.ErrorResponse{Error: "API key doesnt have the scope to do this: " + scope})
+		}
+		if apiKey.UserID == "" {
+			return c.Status(http.StatusForbidden).JSON(types.ErrorResponse{Error: "API key doesnt belong to a user"})
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,346 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/apikeys"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/froggy-12/mooshroombase_v2/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+func setup(t *testing.T) *fiber.App {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	sessions.Store = sessions.NewRedisStorage(client)
+
+	previous := configs.Configs
+	configs.Configs.HttpConfigurations = configs.HttpConfigurations{JWTSecret: "secret", JWTTokenExpirationTime: 7, AccessTokenExpirationTime: 15, JWTIssuer: "mooshroombase", JWTAudience: "mooshroombase"}
+	configs.Configs.Authentication.RolePermissions = nil
+	t.Cleanup(func() { configs.Configs = previous })
+
+	app := fiber.New()
+	app.Use(CheckAndRefreshJWTTokenMiddleware)
+	app.Get("/me", func(c *fiber.Ctx) error {
+		userID, _ := c.Locals("userId").(string)
+		return c.SendString(userID)
+	})
+	app.Get("/upload", RequirePermission("files:upload"), func(c *fiber.Ctx) error {
+		return c.SendString("uploaded")
+	})
+	app.Get("/data", RequireAPIKeyScope("data"), func(c *fiber.Ctx) error {
+		return c.SendString("data")
+	})
+	return app
+}
+
+func get(t *testing.T, app *fiber.App, path string, prepare func(req *http.Request)) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	prepare(req)
+	res, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	return res.StatusCode, string(body)
+}
+
+func withAccessToken(token string) func(req *http.Request) {
+	return func(req *http.Request) {
+		req.AddCookie(&http.Cookie{Name: sessions.AccessTokenCookieName, Value: token})
+	}
+}
+
+func mustCreateSession(t *testing.T, userID string) sessions.TokenPair {
+	t.Helper()
+	pair, err := sessions.CreateSession(userID, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pair
+}
+
+func sign(t *testing.T, secret string, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// claims like the ones of the access tokens, expiring after expiresIn
+func accessClaims(sessionID string, expiresIn time.Duration) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": "user",
+		"jti": uuid.New().String(),
+		"sid": sessionID,
+		"iss": "mooshroombase",
+		"aud": "mooshroombase",
+		"exp": time.Now().Add(expiresIn).Unix(),
+		"iat": time.Now().Add(expiresIn - 15*time.Minute).Unix(),
+	}
+}
+
+func TestAccessToken(t *testing.T) {
+	tests := []struct {
+		name string
+		// returns the access token sent with the request
+		prepare    func(t *testing.T) string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "valid token",
+			prepare:    func(t *testing.T) string { return mustCreateSession(t, "user").AccessToken },
+			wantStatus: http.StatusOK,
+			wantBody:   "user",
+		},
+		{
+			name:       "no token",
+			prepare:    func(t *testing.T) string { return "" },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "expired token",
+			prepare: func(t *testing.T) string {
+				pair := mustCreateSession(t, "user")
+				return sign(t, "secret", accessClaims(pair.SessionID, -time.Minute))
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "expired",
+		},
+		{
+			name: "signed with another secret",
+			prepare: func(t *testing.T) string {
+				pair := mustCreateSession(t, "user")
+				return sign(t, "other", accessClaims(pair.SessionID, time.Minute))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "token without a session",
+			prepare: func(t *testing.T) string {
+				return sign(t, "secret", accessClaims("", time.Minute))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "token of another issuer",
+			prepare: func(t *testing.T) string {
+				claims := accessClaims(mustCreateSession(t, "user").SessionID, time.Minute)
+				claims["iss"] = "someone else"
+				return sign(t, "secret", claims)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "token for another audience",
+			prepare: func(t *testing.T) string {
+				claims := accessClaims(mustCreateSession(t, "user").SessionID, time.Minute)
+				claims["aud"] = "other app"
+				return sign(t, "secret", claims)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "token from before sessions existed",
+			prepare: func(t *testing.T) string {
+				configs.Configs.HttpConfigurations.JWTLegacyTokensUntil = time.Now().Add(time.Hour).Format(time.RFC3339)
+				return sign(t, "secret", jwt.MapClaims{"sub": "user", "expr": time.Now().Add(time.Hour).Unix(), "iat": time.Now().Unix()})
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "/api/auth/refresh",
+		},
+		{
+			name: "logged out token",
+			prepare: func(t *testing.T) string {
+				pair := mustCreateSession(t, "user")
+				claims, _, err := utils.ParseAccessToken(pair.AccessToken)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := sessions.RevokeToken(claims.JTI); err != nil {
+					t.Fatal(err)
+				}
+				return pair.AccessToken
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "revoked",
+		},
+		{
+			name: "revoked session",
+			prepare: func(t *testing.T) string {
+				pair := mustCreateSession(t, "user")
+				if err := sessions.RevokeSession(pair.SessionID); err != nil {
+					t.Fatal(err)
+				}
+				return pair.AccessToken
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "revoked",
+		},
+		{
+			name: "all sessions of the user revoked",
+			prepare: func(t *testing.T) string {
+				pair := mustCreateSession(t, "user")
+				if err := sessions.RevokeAllForUser("user"); err != nil {
+					t.Fatal(err)
+				}
+				return pair.AccessToken
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "revoked",
+		},
+		{
+			name: "session created after all sessions were revoked",
+			prepare: func(t *testing.T) string {
+				if err := sessions.RevokeAllForUser("user"); err != nil {
+					t.Fatal(err)
+				}
+				return mustCreateSession(t, "user").AccessToken
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "user",
+		},
+		{
+			name: "other session of the user revoked",
+			prepare: func(t *testing.T) string {
+				pair := mustCreateSession(t, "user")
+				if err := sessions.RevokeSession(mustCreateSession(t, "user").SessionID); err != nil {
+					t.Fatal(err)
+				}
+				return pair.AccessToken
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "user",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := setup(t)
+			status, body := get(t, app, "/me", withAccessToken(test.prepare(t)))
+			if status != test.wantStatus || !strings.Contains(body, test.wantBody) {
+				t.Errorf("GET /me = %d %s, want %d with %q", status, body, test.wantStatus, test.wantBody)
+			}
+		})
+	}
+}
+
+func TestAccessTokenAsBearerToken(t *testing.T) {
+	app := setup(t)
+	token := mustCreateSession(t, "user").AccessToken
+
+	status, body := get(t, app, "/me", func(req *http.Request) {
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	})
+	if status != http.StatusOK || body != "user" {
+		t.Errorf("GET /me = %d %s, want the user", status, body)
+	}
+}
+
+func TestAccessTokenPermissions(t *testing.T) {
+	app := setup(t)
+	token := mustCreateSession(t, "user").AccessToken
+
+	// users get the default user role which can upload files
+	if status, body := get(t, app, "/upload", withAccessToken(token)); status != http.StatusOK {
+		t.Errorf("GET /upload = %d %s, want it allowed", status, body)
+	}
+	// scopes only apply to api keys
+	if status, body := get(t, app, "/data", withAccessToken(token)); status != http.StatusOK {
+		t.Errorf("GET /data = %d %s, want it allowed", status, body)
+	}
+}
+
+// keys are looked up in the map, the returned function adds a key to it
+func useAPIKeys(t *testing.T) func(details types.CreateAPIKeyDetails, expiresAt *time.Time) string {
+	t.Helper()
+	keys := map[string]types.APIKey{}
+	previousFind, previousMarkUsed, previousDisabled := apikeys.FindByHash, apikeys.MarkUsed, sessions.UserDisabled
+	apikeys.FindByHash = func(keyHash string) (types.APIKey, error) {
+		apiKey, ok := keys[keyHash]
+		if !ok {
+			return types.APIKey{}, store.ErrNotFound
+		}
+		return apiKey, nil
+	}
+	apikeys.MarkUsed = func(id string, usedAt time.Time) error { return nil }
+	sessions.UserDisabled = func(userID string) (bool, error) { return userID == "disabled", nil }
+	t.Cleanup(func() {
+		apikeys.FindByHash, apikeys.MarkUsed, sessions.UserDisabled = previousFind, previousMarkUsed, previousDisabled
+	})
+
+	return func(details types.CreateAPIKeyDetails, expiresAt *time.Time) string {
+		apiKey, key, err := apikeys.New(details)
+		if err != nil {
+			t.Fatal(err)
+		}
+		apiKey.ExpiresAt = expiresAt
+		keys[apiKey.KeyHash] = apiKey
+		return key
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name       string
+		details    types.CreateAPIKeyDetails
+		expiresAt  *time.Time
+		unknown    bool
+		bearer     bool
+		path       string
+		wantStatus int
+	}{
+		{name: "key with the scope", details: types.CreateAPIKeyDetails{Scopes: []string{"files:upload"}}, path: "/upload", wantStatus: http.StatusOK},
+		{name: "key sent as bearer token", details: types.CreateAPIKeyDetails{Scopes: []string{"files:upload"}}, bearer: true, path: "/upload", wantStatus: http.StatusOK},
+		{name: "key with every scope", details: types.CreateAPIKeyDetails{Scopes: []string{"*"}}, path: "/upload", wantStatus: http.StatusOK},
+		{name: "key without the scope", details: types.CreateAPIKeyDetails{Scopes: []string{"email:send"}}, path: "/upload", wantStatus: http.StatusForbidden},
+		{name: "expired key", details: types.CreateAPIKeyDetails{Scopes: []string{"*"}}, expiresAt: &past, path: "/upload", wantStatus: http.StatusUnauthorized},
+		{name: "unknown key", details: types.CreateAPIKeyDetails{Scopes: []string{"*"}}, unknown: true, path: "/upload", wantStatus: http.StatusUnauthorized},
+		{name: "data scope as the user", details: types.CreateAPIKeyDetails{Scopes: []string{"data"}, UserID: "user"}, path: "/data", wantStatus: http.StatusOK},
+		{name: "data without the scope", details: types.CreateAPIKeyDetails{Scopes: []string{"files:upload"}, UserID: "user"}, path: "/data", wantStatus: http.StatusForbidden},
+		{name: "data with a key of no user", details: types.CreateAPIKeyDetails{Scopes: []string{"*"}}, path: "/data", wantStatus: http.StatusForbidden},
+		{name: "key of a disabled user", details: types.CreateAPIKeyDetails{Scopes: []string{"*"}, UserID: "disabled"}, path: "/data", wantStatus: http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := setup(t)
+			addKey := useAPIKeys(t)
+			key := addKey(test.details, test.expiresAt)
+			if test.unknown {
+				key = apikeys.KeyPrefix + "unknown"
+			}
+
+			status, body := get(t, app, test.path, func(req *http.Request) {
+				if test.bearer {
+					req.Header.Set(fiber.HeaderAuthorization, "Bearer "+key)
+				} else {
+					req.Header.Set("X-API-Key", key)
+				}
+			})
+			if status != test.wantStatus {
+				t.Errorf("GET %s = %d %s, want %d", test.path, status, body, test.wantStatus)
+			}
+		})
+	}
+}
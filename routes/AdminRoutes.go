@@ -0,0 +1,43 @@
+package routes
+
+import (
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/auth"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/gofiber/fiber/v2"
+)
+
+func AdminRoutes(router fiber.Router, users store.UserStore) {
+	router.Put("/users/:id/roles", func(c *fiber.Ctx) error {
+		return auth.SetUserRoles(c, users, *validate)
+	})
+	router.Get("/users", func(c *fiber.Ctx) error {
+		return auth.AdminListUsers(c, users)
+	})
+	router.Get("/users/:id", func(c *fiber.Ctx) error {
+		return auth.AdminGetUser(c, users)
+	})
+	router.Post("/users/:id/verify", func(c *fiber.Ctx) error {
+		return auth.AdminVerifyUser(c, users)
+	})
+	router.Post("/users/:id/disable", func(c *fiber.Ctx) error {
+		return auth.AdminDisableUser(c, users)
+	})
+	router.Post("/users/:id/enable", func(c *fiber.Ctx) error {
+		return auth.AdminEnableUser(c, users)
+	})
+	router.Put("/users/:id/password", func(c *fiber.Ctx) error {
+		return auth.AdminResetPassword(c, users, *validate)
+	})
+	router.Delete("/users/:id", func(c *fiber.Ctx) error {
+		return auth.AdminDeleteUser(c, users)
+	})
+	router.Post("/api-keys", func(c *fiber.Ctx) error {
+		return auth.CreateAPIKey(c, users, *validate)
+	})
+	router.Get("/api-keys", func(c *fiber.Ctx) error {
+		return auth.ListAPIKeys(c, users)
+	})
+	router.Delete("/api-keys/:id", func(c *fiber.Ctx) error {
+		return auth.DeleteAPIKey(c, users)
+	})
+}
@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/auth"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+var validate = validator.New()
+
+func AuthRoutes(router fiber.Router, users store.UserStore) {
+	router.Post("/create-user", func(c *fiber.Ctx) error {
+		return auth.CreateUserWithEmailAndPassword(c, users, *validate)
+	})
+	router.Post("/log-in", func(c *fiber.Ctx) error {
+		return auth.LogInWithEmailAndPassword(c, users, *validate)
+	})
+	router.Post("/send-verification-email", func(c *fiber.Ctx) error {
+		return auth.SendVerificationEmail(c, users)
+	})
+	router.Get("/verified", func(c *fiber.Ctx) error {
+		return auth.VerifyEmail(c, users, *validate)
+	})
+	router.Get("/check-email-availability", func(c *fiber.Ctx) error {
+		return auth.CheckIsEmailAvailable(c, users, *validate)
+	})
+	router.Get("/check-username-availability", func(c *fiber.Ctx) error {
+		return auth.CheckIsUsernameAvailable(c, users, *validate)
+	})
+	router.Post("/request-password-reset", func(c *fiber.Ctx) error {
+		return auth.RequestPasswordReset(c, users, *validate)
+	})
+	router.Post("/reset-password", func(c *fiber.Ctx) error {
+		return auth.ResetPassword(c, users, *validate)
+	})
+	router.Post("/verify-2fa", func(c *fiber.Ctx) error {
+		return auth.VerifyTwoFactorLogIn(c, users, *validate)
+	})
+}
@@ -3,7 +3,8 @@ package routes
 import (
 	"net/http"
 
-	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/jwtkeys"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
 	smtpconfigs "github.com/froggy-12/mooshroombase_v2/smtp_configs"
 	"github.com/froggy-12/mooshroombase_v2/types"
 	"github.com/froggy-12/mooshroombase_v2/utils"
@@ -40,8 +41,7 @@ func SendEmail(c *fiber.Ctx) error {
 }
 
 func GetUserID(c *fiber.Ctx) error {
-	token := c.Cookies("jwtToken")
-	id, err := utils.ExtractJWTToken(token, configs.Configs.HttpConfigurations.JWTSecret)
+	id, err := utils.CurrentUserID(c)
 
 	if err != nil {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Failed to pass user id: " + err.Error()})
@@ -50,3 +50,157 @@ func GetUserID(c *fiber.Ctx) error {
 	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Data: map[string]any{"userID": id}})
 
 }
+
+// refresh tokens are read from the cookie or from the body for clients which cant use cookies, those get the new pair in the response
+// clients still holding an access token from before sessions existed send it instead and get their first session
+func RefreshTokens(c *fiber.Ctx) error {
+	var body struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	fromBody := false
+	refreshToken := c.Cookies(sessions.RefreshTokenCookieName)
+	if refreshToken == "" {
+		if err := c.BodyParser(&body); err != nil || body.RefreshToken == "" {
+			if claims, expired, err := utils.ParseAccessToken(utils.AccessToken(c)); err == nil && !expired && claims.Legacy {
+				return exchangeLegacyToken(c, claims.UserID)
+			}
+			return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Refresh token is missing"})
+		}
+		refreshToken = body.RefreshToken
+		fromBody = true
+	}
+
+	pair, err := sessions.Rotate(refreshToken)
+	if err != nil {
+		sessions.ClearTokenCookies(c)
+		if err == sessions.ErrInvalidRefreshToken || err == sessions.ErrRefreshTokenReused || err == sessions.ErrSessionRevoked {
+			return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: err.Error()})
+		}
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to refresh the tokens: " + err.Error()})
+	}
+
+	if fromBody {
+		return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "Tokens have been refreshed", Data: map[string]any{"accessToken": pair.AccessToken, "refreshToken": pair.RefreshToken}})
+	}
+
+	sessions.SetTokenCookies(c, pair)
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "Tokens have been refreshed"})
+}
+
+func exchangeLegacyToken(c *fiber.Ctx, userID string) error {
+	pair, err := sessions.ExchangeLegacyToken(c, utils.AccessToken(c), userID)
+	if err == sessions.ErrLegacyTokenUsed || err == sessions.ErrSessionRevoked {
+		sessions.ClearTokenCookies(c)
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to start the session: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "Tokens have been refreshed", Data: utils.WithTokens(c, pair, map[string]any{})})
+}
+
+// revokes the current session, it is found from the access token or the refresh token cookie
+func LogOut(c *fiber.Ctx) error {
+	sessionID := ""
+	if token := utils.AccessToken(c); token != "" {
+		claims, _, err := utils.ParseAccessToken(token)
+		if err == nil && claims.JTI != "" {
+			if err := sessions.RevokeToken(claims.JTI); err != nil {
+				return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to revoke the token: " + err.Error()})
+			}
+		}
+		sessionID = claims.SessionID
+	}
+	if sessionID == "" {
+		if refreshToken := c.Cookies(sessions.RefreshTokenCookieName); refreshToken != "" {
+			sessionID, _ = sessions.SessionIDFromRefreshToken(refreshToken)
+		}
+	}
+	if sessionID != "" {
+		if err := sessions.RevokeSession(sessionID); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to revoke the session: " + err.Error()})
+		}
+	}
+
+	sessions.ClearTokenCookies(c)
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "User has been logged out successfully"})
+}
+
+func LogOutEverywhere(c *fiber.Ctx) error {
+	userId, ok := c.Locals("userId").(string)
+	if !ok || userId == "" {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "User is not authorised please log in"})
+	}
+
+	if err := sessions.RevokeAllForUser(userId); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to revoke the sessions: " + err.Error()})
+	}
+
+	sessions.ClearTokenCookies(c)
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "User has been logged out from every device successfully"})
+}
+
+func ListSessions(c *fiber.Ctx) error {
+	userId, ok := c.Locals("userId").(string)
+	if !ok || userId == "" {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "User is not authorised please log in"})
+	}
+	currentSessionID, _ := c.Locals("sessionId").(string)
+
+	list, err := sessions.ListSessions(userId, currentSessionID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to load the sessions: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Data: map[string]any{"sessions": list}})
+}
+
+func RevokeSession(c *fiber.Ctx) error {
+	userId, ok := c.Locals("userId").(string)
+	if !ok || userId == "" {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "User is not authorised please log in"})
+	}
+
+	sessionID := c.Params("id")
+	err := sessions.RevokeUserSession(userId, sessionID)
+	if err == sessions.ErrSessionNotFound {
+		return c.Status(http.StatusNotFound).JSON(types.ErrorResponse{Error: "Session not found"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to revoke the session: " + err.Error()})
+	}
+
+	// revoking the current session is the same as logging out
+	if current, _ := c.Locals("sessionId").(string); current == sessionID {
+		sessions.ClearTokenCookies(c)
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "Session has been revoked successfully"})
+}
+
+// lets an admin unlock an account before its lock expires
+func UnlockUser(c *fiber.Ctx) error {
+	if err := sessions.UnlockAccount(c.Params("id")); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to unlock the account: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "Account has been unlocked successfully"})
+}
+
+// public keys the access tokens are signed with so other services can verify them
+func JWKS(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(http.StatusOK).JSON(jwtkeys.JWKS())
+}
+
+// new tokens are signed with the new key right away, other instances sharing the keys directory switch to it within a minute
+// and verify it as soon as they see it, tokens signed with the older keys stay valid
+func RotateSigningKey(c *fiber.Ctx) error {
+	id, err := jwtkeys.Rotate()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to rotate the signing key: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "Signing key has been rotated successfully", Data: map[string]any{"kid": id}})
+}
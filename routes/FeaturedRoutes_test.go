@@ -0,0 +1,130 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+func setupRefresh(t *testing.T) *fiber.App {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	sessions.Store = sessions.NewRedisStorage(client)
+
+	previous := configs.Configs
+	configs.Configs.HttpConfigurations = configs.HttpConfigurations{
+		JWTSecret:                 "secret",
+		JWTTokenExpirationTime:    7,
+		AccessTokenExpirationTime: 15,
+		JWTLegacyTokensUntil:      time.Now().Add(time.Hour).Format(time.RFC3339),
+	}
+	t.Cleanup(func() { configs.Configs = previous })
+
+	app := fiber.New()
+	app.Post("/api/auth/refresh", RefreshTokens)
+	return app
+}
+
+func legacyToken(t *testing.T, expr time.Time) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "user", "expr": expr.Unix(), "iat": time.Now().Unix()}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func refresh(t *testing.T, app *fiber.App, accessToken string) (int, types.HttpSuccessResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/refresh?returnTokens=true", nil)
+	req.AddCookie(&http.Cookie{Name: sessions.AccessTokenCookieName, Value: accessToken})
+	res, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body types.HttpSuccessResponse
+	json.NewDecoder(res.Body).Decode(&body)
+	return res.StatusCode, body
+}
+
+func TestRefreshTokensExchangesLegacyTokens(t *testing.T) {
+	tests := []struct {
+		name       string
+		prepare    func(t *testing.T, app *fiber.App) string
+		wantStatus int
+	}{
+		{
+			name: "legacy token",
+			prepare: func(t *testing.T, app *fiber.App) string {
+				return legacyToken(t, time.Now().Add(time.Hour))
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "legacy token exchanged before",
+			prepare: func(t *testing.T, app *fiber.App) string {
+				token := legacyToken(t, time.Now().Add(time.Hour))
+				if status, _ := refresh(t, app, token); status != http.StatusOK {
+					t.Fatalf("first exchange = %d", status)
+				}
+				return token
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "legacy token after logging out everywhere",
+			prepare: func(t *testing.T, app *fiber.App) string {
+				if err := sessions.RevokeAllForUser("user"); err != nil {
+					t.Fatal(err)
+				}
+				return legacyToken(t, time.Now().Add(time.Hour))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "expired legacy token",
+			prepare: func(t *testing.T, app *fiber.App) string {
+				return legacyToken(t, time.Now().Add(-time.Minute))
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "legacy token after the deadline",
+			prepare: func(t *testing.T, app *fiber.App) string {
+				configs.Configs.HttpConfigurations.JWTLegacyTokensUntil = time.Now().Add(-time.Minute).Format(time.RFC3339)
+				return legacyToken(t, time.Now().Add(time.Hour))
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := setupRefresh(t)
+			status, body := refresh(t, app, test.prepare(t, app))
+			if status != test.wantStatus {
+				t.Fatalf("status = %d, want %d", status, test.wantStatus)
+			}
+			if status != http.StatusOK {
+				return
+			}
+
+			refreshToken, _ := body.Data["refreshToken"].(string)
+			if _, err := sessions.Rotate(refreshToken); err != nil {
+				t.Errorf("Rotate() of the new session = %v", err)
+			}
+		})
+	}
+}
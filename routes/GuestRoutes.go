@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/auth"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/gofiber/fiber/v2"
+)
+
+// the upgrade route must be registered behind the jwt middleware, linking a provider upgrades guests too
+func GuestRoutes(authRouter fiber.Router, userRouter fiber.Router, users store.UserStore) {
+	authRouter.Post("/anonymous", func(c *fiber.Ctx) error {
+		return auth.CreateGuestUser(c, users)
+	})
+	userRouter.Post("/upgrade", func(c *fiber.Ctx) error {
+		return auth.UpgradeGuestUser(c, users, *validate)
+	})
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/auth"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/gofiber/fiber/v2"
+)
+
+func MagicLinkRoutes(router fiber.Router, users store.UserStore) {
+	router.Post("/", func(c *fiber.Ctx) error {
+		return auth.RequestMagicLink(c, users, *validate)
+	})
+	router.Get("/verify", auth.ConfirmMagicLink)
+	router.Post("/verify", func(c *fiber.Ctx) error {
+		return auth.VerifyMagicLink(c, users, *validate)
+	})
+}
@@ -0,0 +1,56 @@
+package routes
+
+import (
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/auth"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/oauth"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/gofiber/fiber/v2"
+)
+
+func OAuthRoutes(router fiber.Router, users store.UserStore) {
+	if configs.Configs.Authentication.GoogleOAuth {
+		router.Get("/google/start", oauth.StartGoogleOAuth)
+		router.Get("/google/callback", func(c *fiber.Ctx) error {
+			return auth.GoogleOAuthCallback(c, users)
+		})
+	}
+	if configs.Configs.Authentication.GithubOAuth {
+		router.Get("/github/start", oauth.StartGithubOAuth)
+		router.Get("/github/callback", func(c *fiber.Ctx) error {
+			return auth.GithubOAuthCallback(c, users)
+		})
+	}
+	for _, provider := range configs.Configs.OIDCProviders {
+		router.Get("/oidc/"+provider.Name+"/start", func(c *fiber.Ctx) error {
+			return oauth.StartOIDC(c, provider, oauth.LogInIntent)
+		})
+		router.Get("/oidc/"+provider.Name+"/callback", func(c *fiber.Ctx) error {
+			return auth.OIDCCallback(c, users, provider)
+		})
+	}
+}
+
+// routes for linking and unlinking providers, they must be registered behind the jwt middleware
+func OAuthLinkRoutes(router fiber.Router, users store.UserStore) {
+	if configs.Configs.Authentication.GoogleOAuth {
+		router.Get("/google/link", oauth.StartGoogleLink)
+		router.Delete("/google/unlink", func(c *fiber.Ctx) error {
+			return auth.UnlinkOAuthProvider(c, users, oauth.GoogleProvider)
+		})
+	}
+	if configs.Configs.Authentication.GithubOAuth {
+		router.Get("/github/link", oauth.StartGithubLink)
+		router.Delete("/github/unlink", func(c *fiber.Ctx) error {
+			return auth.UnlinkOAuthProvider(c, users, oauth.GithubProvider)
+		})
+	}
+	for _, provider := range configs.Configs.OIDCProviders {
+		router.Get("/oidc/"+provider.Name+"/link", func(c *fiber.Ctx) error {
+			return oauth.StartOIDC(c, provider, oauth.LinkIntent)
+		})
+		router.Delete("/oidc/"+provider.Name+"/unlink", func(c *fiber.Ctx) error {
+			return auth.UnlinkOAuthProvider(c, users, oauth.OIDCProviderID(provider.Name))
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/auth"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/passkeys"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/gofiber/fiber/v2"
+)
+
+func PasskeyRoutes(router fiber.Router, users store.UserStore) {
+	router.Post("/log-in/begin", passkeys.BeginLogIn)
+	router.Post("/log-in/finish", func(c *fiber.Ctx) error {
+		return auth.FinishPasskeyLogIn(c, users)
+	})
+}
+
+func PasskeyUserRoutes(router fiber.Router, users store.UserStore) {
+	router.Get("/", func(c *fiber.Ctx) error {
+		return auth.ListPasskeys(c, users)
+	})
+	router.Post("/register/begin", func(c *fiber.Ctx) error {
+		return auth.BeginPasskeyRegistration(c, users)
+	})
+	router.Post("/register/finish", func(c *fiber.Ctx) error {
+		return auth.FinishPasskeyRegistration(c, users)
+	})
+	router.Delete("/:id", func(c *fiber.Ctx) error {
+		return auth.DeletePasskey(c, users)
+	})
+}
@@ -1,29 +1,40 @@
 package routes
 
 import (
-	mongoauth "github.com/froggy-12/mooshroombase_v2/services/authentication/mongo_auth"
-	"github.com/go-playground/validator/v10"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/auth"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func UserRoutes(router fiber.Router, mongoClient *mongo.Client) {
+func UserRoutes(router fiber.Router, users store.UserStore) {
 	router.Get("/get-user", func(c *fiber.Ctx) error {
-		return mongoauth.Get_User(c, mongoClient)
+		return auth.Get_User(c, users)
 	})
 	router.Put("/update-username", func(c *fiber.Ctx) error {
-		return mongoauth.UpdateUserName(c, mongoClient, *validate)
+		return auth.UpdateUserName(c, users, *validate)
 	})
 	router.Put("/update-user-info", func(c *fiber.Ctx) error {
-		return mongoauth.UpdateUser(c, mongoClient)
+		return auth.UpdateUser(c, users)
 	})
 	router.Put("/update-email", func(c *fiber.Ctx) error {
-		return mongoauth.ChangeEmail(c, mongoClient, *validator.New())
+		return auth.ChangeEmail(c, users, *validate)
 	})
 	router.Put("/append-raw-data", func(c *fiber.Ctx) error {
-		return mongoauth.AppendRawData(c, mongoClient)
+		return auth.AppendRawData(c, users)
 	})
 	router.Delete("/delete-user", func(c *fiber.Ctx) error {
-		return mongoauth.DeleteUser(c, mongoClient, *validate)
+		return auth.DeleteUser(c, users, *validate)
+	})
+	router.Put("/change-password", func(c *fiber.Ctx) error {
+		return auth.ChangePassword(c, users, *validate)
+	})
+	router.Post("/2fa/enroll", func(c *fiber.Ctx) error {
+		return auth.EnrollTwoFactor(c, users)
+	})
+	router.Post("/2fa/confirm", func(c *fiber.Ctx) error {
+		return auth.ConfirmTwoFactor(c, users, *validate)
+	})
+	router.Post("/2fa/disable", func(c *fiber.Ctx) error {
+		return auth.DisableTwoFactor(c, users, *validate)
 	})
 }
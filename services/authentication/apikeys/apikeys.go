@@ -0,0 +1,138 @@
+package apikeys
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/froggy-12/mooshroombase_v2/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// every key starts with it so keys can be told apart from jwt tokens in the authorization header
+const KeyPrefix = "mb_"
+
+// scopes a key can be given, data lets the key use the /api/data routes as the user it belongs to
+var Scopes = []string{"*", "data", "files:upload", "files:delete", "email:send"}
+
+var (
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	ErrExpiredAPIKey = errors.New("api key has expired")
+)
+
+// set from the api depending on the primary database
+var (
+	FindByHash func(keyHash string) (types.APIKey, error)
+	MarkUsed   func(id string, usedAt time.Time) error
+)
+
+// the last used time is only written once a minute so busy keys dont write on every request
+const lastUsedResolution = time.Minute
+
+// generates a new key, only its hash is stored and the first characters are kept to recognise it
+func Generate() (key string, keyHash string, displayPrefix string, err error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", "", "", err
+	}
+	key = KeyPrefix + token
+	return key, utils.HashToken(key), key[:len(KeyPrefix)+8], nil
+}
+
+func IsKnownScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
+
+// reads the key from the X-API-Key header or from Authorization: Bearer mb_...
+func FromRequest(c *fiber.Ctx) string {
+	if key := c.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "); ok && strings.HasPrefix(token, KeyPrefix) {
+		return token
+	}
+	return ""
+}
+
+// finds the key sent with the request, the returned bool is false when the request didnt send a key
+func Authenticate(c *fiber.Ctx) (types.APIKey, bool, error) {
+	key := FromRequest(c)
+	if key == "" {
+		return types.APIKey{}, false, nil
+	}
+	if FindByHash == nil {
+		return types.APIKey{}, true, ErrInvalidAPIKey
+	}
+
+	apiKey, err := FindByHash(utils.HashToken(key))
+	if errors.Is(err, store.ErrNotFound) {
+		return types.APIKey{}, true, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return types.APIKey{}, true, err
+	}
+
+	if apiKey.ExpiresAt != nil && time.Now().After(*apiKey.ExpiresAt) {
+		return types.APIKey{}, true, ErrExpiredAPIKey
+	}
+
+	if MarkUsed != nil && (apiKey.LastUsedAt == nil || time.Since(*apiKey.LastUsedAt) > lastUsedResolution) {
+		now := time.Now()
+		if err := MarkUsed(apiKey.ID, now); err != nil {
+			return types.APIKey{}, true, err
+		}
+		apiKey.LastUsedAt = &now
+	}
+
+	return apiKey, true, nil
+}
+
+func HasScope(apiKey types.APIKey, scope string) bool {
+	return slices.Contains(apiKey.Scopes, "*") || slices.Contains(apiKey.Scopes, scope)
+}
+
+// checks the scopes and expiry of a new key, keys with the data scope have to belong to a user
+func CheckDetails(details types.CreateAPIKeyDetails) error {
+	for _, scope := range details.Scopes {
+		if !IsKnownScope(scope) {
+			return errors.New("unknown scope: " + scope)
+		}
+	}
+	if slices.Contains(details.Scopes, "data") && details.UserID == "" {
+		return errors.New("keys with the data scope need a userId")
+	}
+	if details.ExpiresAt != nil && details.ExpiresAt.Before(time.Now()) {
+		return errors.New("expiresAt is in the past")
+	}
+	return nil
+}
+
+// builds the key which gets stored, the plain key is returned separately because it is only shown once
+func New(details types.CreateAPIKeyDetails) (types.APIKey, string, error) {
+	key, keyHash, displayPrefix, err := Generate()
+	if err != nil {
+		return types.APIKey{}, "", err
+	}
+
+	scopes := []string{}
+	for _, scope := range details.Scopes {
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return types.APIKey{
+		ID:        uuid.New().String(),
+		Name:      details.Name,
+		KeyHash:   keyHash,
+		Prefix:    displayPrefix,
+		Scopes:    scopes,
+		UserID:    details.UserID,
+		CreatedAt: time.Now(),
+		ExpiresAt: details.ExpiresAt,
+	}, key, nil
+}
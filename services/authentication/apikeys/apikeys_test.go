@@ -0,0 +1,85 @@
+package apikeys
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/froggy-12/mooshroombase_v2/utils"
+)
+
+func TestGenerate(t *testing.T) {
+	key, keyHash, displayPrefix, err := Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, KeyPrefix) || !strings.HasPrefix(key, displayPrefix) || len(displayPrefix) != len(KeyPrefix)+8 {
+		t.Errorf("Generate() = %q with prefix %q", key, displayPrefix)
+	}
+	if keyHash != utils.HashToken(key) {
+		t.Error("Generate() hash doesnt belong to the key")
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{"granted scope", []string{"files:upload"}, "files:upload", true},
+		{"every scope", []string{"*"}, "email:send", true},
+		{"missing scope", []string{"files:upload"}, "files:delete", false},
+		{"scopes are not prefixes", []string{"files"}, "files:upload", false},
+		{"no scopes", nil, "data", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := HasScope(types.APIKey{Scopes: test.scopes}, test.scope); got != test.want {
+				t.Errorf("HasScope(%v, %q) = %v, want %v", test.scopes, test.scope, got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckDetails(t *testing.T) {
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name    string
+		details types.CreateAPIKeyDetails
+		wantErr bool
+	}{
+		{"known scopes", types.CreateAPIKeyDetails{Name: "ci", Scopes: []string{"files:upload", "email:send"}}, false},
+		{"unknown scope", types.CreateAPIKeyDetails{Name: "ci", Scopes: []string{"users:delete"}}, true},
+		{"data scope of a user", types.CreateAPIKeyDetails{Name: "ci", Scopes: []string{"data"}, UserID: "user"}, false},
+		{"data scope without a user", types.CreateAPIKeyDetails{Name: "ci", Scopes: []string{"data"}}, true},
+		{"expiry in the future", types.CreateAPIKeyDetails{Name: "ci", Scopes: []string{"*"}, ExpiresAt: &future}, false},
+		{"expiry in the past", types.CreateAPIKeyDetails{Name: "ci", Scopes: []string{"*"}, ExpiresAt: &past}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := CheckDetails(test.details); (err != nil) != test.wantErr {
+				t.Errorf("CheckDetails() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	apiKey, key, err := New(types.CreateAPIKeyDetails{Name: "ci", Scopes: []string{"data", "files:upload", "data"}, UserID: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiKey.KeyHash != utils.HashToken(key) || strings.Contains(apiKey.KeyHash, key) {
+		t.Error("New() doesnt store only the hash of the key")
+	}
+	if !slices.Equal(apiKey.Scopes, []string{"data", "files:upload"}) {
+		t.Errorf("New() scopes = %v, want them without duplicates", apiKey.Scopes)
+	}
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/passwords"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/froggy-12/mooshroombase_v2/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// lists users newest first, ?search= matches a part of the email or the username
+func AdminListUsers(c *fiber.Ctx, users store.UserStore) error {
+	page, limit := utils.Pagination(c)
+
+	found, total, err := users.ListUsers(c.Query("search"), page, limit)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to list the users: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Data: map[string]any{"users": found, "page": page, "limit": limit, "total": total}})
+}
+
+func AdminGetUser(c *fiber.Ctx, users store.UserStore) error {
+	user, err := users.FindUserByID(c.Params("id"))
+	if err == store.ErrNotFound {
+		return c.Status(http.StatusNotFound).JSON(types.ErrorResponse{Error: "User Not Found"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
+
+	lockStatus, err := sessions.LockStatusOf(user.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to load the lock status: " + err.Error()})
+	}
+
+	roles, err := sessions.RolesOf(user.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to load the roles: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{
+		Message: "User has been Found successfully",
+		Data:    map[string]any{"user": user, "lockStatus": lockStatus, "roles": roles},
+	})
+}
+
+// responds with 404 when the user of the route doesnt exist, the message is used for every other error
+func updateUserAsAdmin(c *fiber.Ctx, users store.UserStore, update store.UserUpdate, message string) (bool, error) {
+	err := users.UpdateUser(c.Params("id"), update)
+	if err == store.ErrNotFound {
+		return false, c.Status(http.StatusNotFound).JSON(types.ErrorResponse{Error: "User Not Found"})
+	}
+	if err != nil {
+		return false, c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: message + err.Error()})
+	}
+	return true, nil
+}
+
+func AdminVerifyUser(c *fiber.Ctx, users store.UserStore) error {
+	if updated, err := updateUserAsAdmin(c, users, store.UserUpdate{Verified: ptr(true), VerificationToken: ptr("")}, "Failed to update user's verification status: "); !updated {
+		return err
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "User has been verified successfully"})
+}
+
+// disabled users are logged out everywhere and cant log in again until they are enabled, the body can hold a reason
+func AdminDisableUser(c *fiber.Ctx, users store.UserStore) error {
+	var body types.DisableUserDetails
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Request body"})
+		}
+	}
+
+	if updated, err := updateUserAsAdmin(c, users, store.UserUpdate{Disabled: ptr(true), DisabledReason: body.Reason}, "Failed to disable the user: "); !updated {
+		return err
+	}
+
+	if err := sessions.RevokeAllForUser(c.Params("id")); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "User has been disabled but failed to log out the sessions: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "User has been disabled successfully"})
+}
+
+func AdminEnableUser(c *fiber.Ctx, users store.UserStore) error {
+	if updated, err := updateUserAsAdmin(c, users, store.UserUpdate{Disabled: ptr(false)}, "Failed to enable the user: "); !updated {
+		return err
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "User has been enabled successfully"})
+}
+
+// removes the user together with their two factor, passkeys, password reset tokens and api keys
+func AdminDeleteUser(c *fiber.Ctx, users store.UserStore) error {
+	userID := c.Params("id")
+
+	err := users.DeleteUser(userID)
+	if err == store.ErrNotFound {
+		return c.Status(http.StatusNotFound).JSON(types.ErrorResponse{Error: "User Not Found"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to delete user: " + err.Error()})
+	}
+
+	if err := sessions.RevokeAllForUser(userID); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "User has been deleted but failed to log out the sessions: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "User has been deleted successfully"})
+}
+
+// sets a new password for the user, every session of the user is logged out and the account is unlocked
+func AdminResetPassword(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
+	var body types.AdminPasswordDetails
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Request body"})
+	}
+
+	if err := validate.Struct(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+
+	hashedPassword, err := passwords.Hash(body.NewPassword)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to Hash the password: " + err.Error()})
+	}
+
+	if updated, err := updateUserAsAdmin(c, users, store.UserUpdate{Password: &hashedPassword}, "Failed to update password: "); !updated {
+		return err
+	}
+
+	if err := sessions.RevokeAllForUser(c.Params("id")); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Password has been changed but failed to log out the sessions: " + err.Error()})
+	}
+	if err := sessions.UnlockAccount(c.Params("id")); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Password has been changed but failed to unlock the account: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "Password Has been Updated"})
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/apikeys"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// the key is only part of this response, afterwards only its prefix can be seen
+func CreateAPIKey(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
+	var body types.CreateAPIKeyDetails
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Request body"})
+	}
+
+	if err := validate.Struct(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+
+	if err := apikeys.CheckDetails(body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+
+	if body.UserID != "" {
+		if _, err := users.FindUserByID(body.UserID); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User Not Found: " + err.Error()})
+		}
+	}
+
+	apiKey, key, err := apikeys.New(body)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to generate the api key: " + err.Error()})
+	}
+
+	if err := users.CreateAPIKey(apiKey); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to store the api key: " + err.Error()})
+	}
+
+	return c.Status(http.StatusCreated).JSON(types.HttpSuccessResponse{Message: "API key has been created, it wont be shown again", Data: map[string]any{"apiKey": apiKey, "key": key}})
+}
+
+func ListAPIKeys(c *fiber.Ctx, users store.UserStore) error {
+	keys, err := users.ListAPIKeys()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to list the api keys: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Data: map[string]any{"apiKeys": keys}})
+}
+
+func DeleteAPIKey(c *fiber.Ctx, users store.UserStore) error {
+	err := users.DeleteAPIKey(c.Params("id"))
+	if err == store.ErrNotFound {
+		return c.Status(http.StatusNotFound).JSON(types.ErrorResponse{Error: "API key not found"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to remove the api key: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "API key has been removed successfully"})
+}
@@ -1,34 +1,35 @@
-package mongoauth
+package auth
 
 import (
-	"context"
 	"net/http"
 	"time"
 
 	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/passwords"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
 	smtpconfigs "github.com/froggy-12/mooshroombase_v2/smtp_configs"
 	"github.com/froggy-12/mooshroombase_v2/types"
 	"github.com/froggy-12/mooshroombase_v2/utils"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"golang.org/x/crypto/bcrypt"
 )
 
-func CreateUserWithEmailAndPassword(c *fiber.Ctx, mongoClient *mongo.Client, validate validator.Validate) error {
-	jwtTokenCookie := c.Cookies("jwtToken")
-	if jwtTokenCookie != "" {
-		userID, _, _ := utils.ReadJWTToken(jwtTokenCookie, configs.Configs.HttpConfigurations.JWTSecret)
-		if userID != "" {
+// pointer to a value, used to fill the fields of a store.UserUpdate
+func ptr[T any](value T) *T {
+	return &value
+}
+
+func CreateUserWithEmailAndPassword(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
+	accessToken := utils.AccessToken(c)
+	if accessToken != "" {
+		if _, expired, err := utils.ParseAccessToken(accessToken); err == nil && !expired {
 			return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Valid Token found please log out first then sign up"})
 		}
 	}
 
-	collection := mongoClient.Database("mooshroombase").Collection("users")
 	var user types.User
-
 	if err := c.BodyParser(&user); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Request body"})
 	}
@@ -37,43 +38,39 @@ func CreateUserWithEmailAndPassword(c *fiber.Ctx, mongoClient *mongo.Client, val
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hashedPassword, err := passwords.Hash(user.Password)
 
 	if err != nil {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Failed to Hash the password: " + err.Error()})
 	}
 
-	Id := uuid.New().String()
-	verificationTokenString := uuid.New().String()
-
-	newUser := types.User_Mongo{
-		ID:                Id,
+	newUser := types.AuthUser{
+		ID:                uuid.New().String(),
 		FirstName:         user.FirstName,
 		LastName:          user.LastName,
 		Email:             user.Email,
-		Password:          string(hashedPassword),
+		Password:          hashedPassword,
 		UserName:          user.UserName,
 		ProfilePicture:    configs.Configs.ExtraConfigurations.DefaultProfilePictureUrl,
 		CreatedAt:         time.Now(),
 		UpdatedAt:         time.Now(),
 		Verified:          false,
-		VerificationToken: verificationTokenString,
+		VerificationToken: uuid.New().String(),
 		LastLoggedIn:      types.LastTimeLoggedIn{When: time.Now()},
 		RawData:           []types.RawUserData{},
+		Roles:             sessions.DefaultRoles(),
 	}
 
-	_, err = collection.InsertOne(context.Background(), newUser)
-
-	if err != nil {
+	if err := users.CreateUser(newUser); err != nil {
 		return c.Status(http.StatusBadGateway).JSON(types.ErrorResponse{Error: "failed to create new user into the database: " + err.Error()})
 	}
 
+	var pair sessions.TokenPair
 	if configs.Configs.Authentication.SetJWTTokenAfterSignUp {
-		token, err := utils.GenerateJWTToken(newUser.ID, 1, configs.Configs.HttpConfigurations.JWTSecret)
+		pair, err = sessions.LogIn(c, newUser.ID)
 		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to generate jwt token for user: " + newUser.ID})
+			return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to create the session for user: " + newUser.ID})
 		}
-		utils.SetJwtHttpCookies(c, token, 1)
 	}
 
 	if configs.Configs.Authentication.SendEmailAfterSignUpWithCode {
@@ -85,50 +82,39 @@ func CreateUserWithEmailAndPassword(c *fiber.Ctx, mongoClient *mongo.Client, val
 			return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "SMTP is not configured or turned off please check again and restart the app"})
 		}
 
-		newToken := uuid.New().String()
-		_, err := collection.UpdateOne(context.Background(), bson.M{"id": newUser.ID}, bson.M{"$set": bson.M{"verificationToken": newToken}})
-
-		if err != nil {
-			return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "failed to update new token: " + err.Error()})
-		}
-
-		err = smtpconfigs.SendVerificationEmail(newUser.Email, newToken)
+		err = smtpconfigs.SendVerificationEmail(newUser.Email, newUser.VerificationToken)
 		if err != nil {
 			return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "failed to send email to this user: " + user.Email})
 		}
 
-		return c.Status(http.StatusAccepted).JSON(types.HttpSuccessResponse{Message: "User has been created successfully and sent verification email"})
+		return c.Status(http.StatusAccepted).JSON(types.HttpSuccessResponse{Message: "User has been created successfully and sent verification email", Data: utils.WithTokens(c, pair, map[string]any{"userId": newUser.ID})})
 
 	} else {
 		return c.Status(http.StatusCreated).JSON(types.HttpSuccessResponse{
 			Message: "User Has been created to the database hope you will verify the email first then everything",
-			Data:    map[string]any{"userId": newUser.ID},
+			Data:    utils.WithTokens(c, pair, map[string]any{"userId": newUser.ID}),
 		})
 	}
 }
 
-func LogInWithEmailAndPassword(c *fiber.Ctx, mongoClient *mongo.Client, validate validator.Validate) error {
-	coll := mongoClient.Database("mooshroombase").Collection("users")
-	token := c.Cookies("jwtToken")
+func LogInWithEmailAndPassword(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
+	token := utils.AccessToken(c)
 	if token != "" {
-		userid, expired, err := utils.ReadJWTToken(token, configs.Configs.HttpConfigurations.JWTSecret)
+		claims, expired, err := utils.ParseAccessToken(token)
 		if err != nil || expired {
-			err = utils.LogIn(c, coll, validate, configs.Configs.HttpConfigurations.JWTTokenExpirationTime, configs.Configs.HttpConfigurations.JWTSecret)
-			return err
+			return LogIn(c, users, validate)
 		}
 
-		_, err = utils.FindUserFromMongoDBUsingID(userid, coll)
+		_, err = users.FindUserByID(claims.UserID)
 		if err == nil {
 			return c.Status(http.StatusAlreadyReported).JSON(types.HttpSuccessResponse{Message: "You are already logged in"})
 		}
-
 	}
 
-	return utils.LogIn(c, coll, validate, configs.Configs.HttpConfigurations.JWTTokenExpirationTime, configs.Configs.HttpConfigurations.JWTSecret)
+	return LogIn(c, users, validate)
 }
 
-func SendVerificationEmail(c *fiber.Ctx, mongoClient *mongo.Client) error {
-
+func SendVerificationEmail(c *fiber.Ctx, users store.UserStore) error {
 	if !configs.Configs.Authentication.EmailVerificationAllowed {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Email Verification is not configured or turned off please check again and restart the app"})
 	}
@@ -143,9 +129,10 @@ func SendVerificationEmail(c *fiber.Ctx, mongoClient *mongo.Client) error {
 
 	tokenSet := c.Query("tokenSet", "false")
 
-	if c.Cookies("jwtToken") != "" && tokenSet == "true" {
-		userID, _, _ := utils.ReadJWTToken(c.Cookies("jwtToken"), configs.Configs.HttpConfigurations.JWTSecret)
-		body.ID = userID
+	if utils.AccessToken(c) != "" && tokenSet == "true" {
+		if claims, expired, err := utils.ParseAccessToken(utils.AccessToken(c)); err == nil && !expired {
+			body.ID = claims.UserID
+		}
 	}
 
 	if body.ID == "" && tokenSet == "false" {
@@ -154,18 +141,21 @@ func SendVerificationEmail(c *fiber.Ctx, mongoClient *mongo.Client) error {
 		}
 	}
 
-	coll := mongoClient.Database("mooshroombase").Collection("users")
-
-	user, err := utils.FindUserFromMongoDBUsingID(body.ID, coll)
-	if err != nil {
+	user, err := users.FindUserByID(body.ID)
+	if err == store.ErrNotFound {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User not found"})
 	}
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
 
-	newToken := uuid.New().String()
-	_, err = coll.UpdateOne(context.Background(), bson.M{"id": user.ID}, bson.M{"$set": bson.M{"verificationToken": newToken}})
+	if user.Verified {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User is already verified"})
+	}
 
-	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "failed to update new token: " + err.Error()})
+	newToken := uuid.New().String()
+	if err := users.UpdateUser(user.ID, store.UserUpdate{VerificationToken: &newToken}); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "failed to generate and set new verification token: " + err.Error()})
 	}
 
 	err = smtpconfigs.SendVerificationEmail(user.Email, newToken)
@@ -176,9 +166,7 @@ func SendVerificationEmail(c *fiber.Ctx, mongoClient *mongo.Client) error {
 	return c.Status(http.StatusAccepted).JSON(types.HttpSuccessResponse{Message: "Email sent successfully"})
 }
 
-func VerifyEmail(c *fiber.Ctx, mongoClient *mongo.Client, validate validator.Validate) error {
-	coll := mongoClient.Database("mooshroombase").Collection("users")
-
+func VerifyEmail(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
 	email := c.Query("email")
 	verificationTokenString := c.Query("token")
 
@@ -190,29 +178,29 @@ func VerifyEmail(c *fiber.Ctx, mongoClient *mongo.Client, validate validator.Val
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid email: " + err.Error()})
 	}
 
-	user, err := utils.FindUserFromMongoDBUsingEmail(email, coll)
-	if err != nil {
+	user, err := users.FindUserByEmail(email)
+	if err == store.ErrNotFound {
 		return c.Status(http.StatusBadGateway).JSON(types.ErrorResponse{Error: "User not Found"})
 	}
+	if err != nil {
+		return c.Status(http.StatusBadGateway).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
 
 	if user.Verified {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User is already verified"})
 	}
 
-	if user.VerificationToken == verificationTokenString {
-		_, err := coll.UpdateOne(context.Background(), bson.M{"email": email}, bson.M{"$set": bson.M{"verified": true}})
-		if err != nil {
-			return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to update user verification status"})
-		}
-		return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "Email verified successfully"})
-	} else {
+	if user.VerificationToken != verificationTokenString {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Wrong token Provided"})
 	}
-}
 
-func CheckIsEmailAvailable(c *fiber.Ctx, mongoClient *mongo.Client, validate validator.Validate) error {
-	coll := mongoClient.Database("mooshroombase").Collection("users")
+	if err := users.UpdateUser(user.ID, store.UserUpdate{Verified: ptr(true)}); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to update user verification status"})
+	}
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "Email verified successfully"})
+}
 
+func CheckIsEmailAvailable(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
 	email := c.Query("email")
 	if email == "" {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "invalid query"})
@@ -220,16 +208,14 @@ func CheckIsEmailAvailable(c *fiber.Ctx, mongoClient *mongo.Client, validate val
 	if err := validate.Var(email, "required,email"); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Email: " + err.Error()})
 	}
-	_, err := utils.FindUserFromMongoDBUsingEmail(email, coll)
+	_, err := users.FindUserByEmail(email)
 	if err == nil {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User already exist"})
 	}
 	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "The Email is good to go"})
 }
 
-func CheckIsUsernameAvailable(c *fiber.Ctx, mongoClient *mongo.Client, validate validator.Validate) error {
-	coll := mongoClient.Database("mooshroombase").Collection("users")
-
+func CheckIsUsernameAvailable(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
 	username := c.Query("username")
 	if username == "" {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "invalid query"})
@@ -237,7 +223,7 @@ func CheckIsUsernameAvailable(c *fiber.Ctx, mongoClient *mongo.Client, validate
 	if err := validate.Var(username, "required"); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
 	}
-	_, err := utils.FindUserFromMongoDBUsingUsername(username, coll)
+	_, err := users.FindUserByUserName(username)
 	if err == nil {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User already exist"})
 	}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/db"
+	"github.com/froggy-12/mooshroombase_v2/middlewares"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/passwords"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// handlers run against a migrated sqlite database and an in memory redis
+func setup(t *testing.T) store.UserStore {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	sessions.Store = sessions.NewRedisStorage(client)
+
+	previous := configs.Configs
+	configs.Configs.HttpConfigurations = configs.HttpConfigurations{JWTSecret: "secret", JWTTokenExpirationTime: 7, AccessTokenExpirationTime: 15}
+	configs.Configs.DatabaseConfigurations.PrimaryDB = "sqlite"
+	configs.Configs.Authentication = configs.Authentication{}
+	t.Cleanup(func() { configs.Configs = previous })
+
+	database := db.ConnectToSQLite(filepath.Join(t.TempDir(), "mooshroombase.db"))
+	t.Cleanup(func() { database.Close() })
+	migrator, err := db.NewMigrator(nil, nil, nil, database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	users := store.NewSQLiteStore(database)
+	sessions.UserRoles = users.FindRoles
+	sessions.UserDisabled = users.IsDisabled
+	t.Cleanup(func() { sessions.UserRoles, sessions.UserDisabled = nil, nil })
+	return users
+}
+
+// the user is stored with a hashed password unless it is empty
+func mustCreateUser(t *testing.T, users store.UserStore, user types.AuthUser) types.AuthUser {
+	t.Helper()
+	if user.ID == "" {
+		user.ID = uuid.New().String()
+	}
+	if user.UserName == "" {
+		user.UserName = "user-" + user.ID[:8]
+	}
+	if user.Email == "" {
+		user.Email = user.UserName + "@example.com"
+	}
+	if user.Password != "" {
+		hashed, err := passwords.Hash(user.Password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		user.Password = hashed
+	}
+	user.CreatedAt, user.UpdatedAt = time.Now(), time.Now()
+	user.LastLoggedIn = types.LastTimeLoggedIn{When: time.Now()}
+	if err := users.CreateUser(user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func mustLogIn(t *testing.T, userID string) sessions.TokenPair {
+	t.Helper()
+	pair, err := sessions.CreateSession(userID, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pair
+}
+
+// an app with the handler behind the jwt middleware like the routes register it
+func protected(method, path string, handler fiber.Handler) *fiber.App {
+	app := fiber.New()
+	app.Add(method, path, middlewares.CheckAndRefreshJWTTokenMiddleware, handler)
+	return app
+}
+
+func send(t *testing.T, app *fiber.App, method, path string, body any, accessToken string) (*http.Response, types.HttpSuccessResponse, string) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if accessToken != "" {
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+accessToken)
+	}
+	res, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(res.Body)
+	var decoded types.HttpSuccessResponse
+	json.Unmarshal(raw, &decoded)
+	return res, decoded, string(raw)
+}
+
+var validate = *validator.New()
@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/passwords"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	smtpconfigs "github.com/froggy-12/mooshroombase_v2/smtp_configs"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/froggy-12/mooshroombase_v2/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// creates a user without credentials so the frontend has an identity before the sign up, it gets logged in right away
+func CreateGuestUser(c *fiber.Ctx, users store.UserStore) error {
+	if _, expired, err := utils.ParseAccessToken(utils.AccessToken(c)); err == nil && !expired {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Valid Token found please log out first then continue as a guest"})
+	}
+
+	// guests dont have a password so password login wont ever match for them
+	id := uuid.New().String()
+	newUser := types.AuthUser{
+		ID:             id,
+		UserName:       utils.GuestUserName(id),
+		FirstName:      "Guest",
+		Email:          utils.GuestEmail(id),
+		ProfilePicture: configs.Configs.ExtraConfigurations.DefaultProfilePictureUrl,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		LastLoggedIn:   types.LastTimeLoggedIn{When: time.Now()},
+		RawData:        []types.RawUserData{},
+		Roles:          []string{sessions.GuestRole},
+		Guest:          true,
+	}
+
+	if err := users.CreateUser(newUser); err != nil {
+		return c.Status(http.StatusBadGateway).JSON(types.ErrorResponse{Error: "failed to create guest user into the database: " + err.Error()})
+	}
+
+	pair, err := sessions.LogIn(c, newUser.ID)
+	if err != nil {
+		return utils.SessionError(c, err)
+	}
+
+	return c.Status(http.StatusCreated).JSON(types.HttpSuccessResponse{
+		Message: "Guest user has been created successfully",
+		Data:    utils.WithTokens(c, pair, map[string]any{"userId": newUser.ID}),
+	})
+}
+
+// turns the logged in guest into a full account, the id stays the same so its raw data and files stay with it
+func UpgradeGuestUser(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
+	userId, err := utils.CurrentUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "Something went Wrong: " + err.Error()})
+	}
+
+	var body types.UpgradeGuestDetails
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Request body"})
+	}
+
+	if err := validate.Struct(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+
+	user, err := users.FindUserByID(userId)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User Not Found: " + err.Error()})
+	}
+	if !user.Guest {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Only guest accounts can be upgraded"})
+	}
+
+	if _, err := users.FindUserByEmail(body.Email); err == nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User already exist with this email"})
+	}
+	if _, err := users.FindUserByUserName(body.UserName); err == nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Username is already taken"})
+	}
+
+	hashedPassword, err := passwords.Hash(body.Password)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to Hash the password: " + err.Error()})
+	}
+
+	if body.FirstName == "" {
+		body.FirstName = user.FirstName
+	}
+	if body.LastName == "" {
+		body.LastName = user.LastName
+	}
+
+	verificationToken := uuid.New().String()
+	err = users.UpgradeGuest(user.ID, store.UserUpdate{
+		UserName:          &body.UserName,
+		FirstName:         &body.FirstName,
+		LastName:          &body.LastName,
+		Email:             &body.Email,
+		Password:          &hashedPassword,
+		Verified:          ptr(false),
+		VerificationToken: &verificationToken,
+		Roles:             sessions.DefaultRoles(),
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to upgrade the guest user: " + err.Error()})
+	}
+
+	pair, err := utils.ReplaceSession(c, user.ID)
+	if err != nil {
+		return utils.SessionError(c, err)
+	}
+
+	if configs.Configs.Authentication.SendEmailAfterSignUpWithCode && configs.Configs.Authentication.EmailVerificationAllowed && configs.Configs.SMTPConfigurations.SMTPEnabled {
+		if err := smtpconfigs.SendVerificationEmail(body.Email, verificationToken); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "failed to send email to this user: " + body.Email})
+		}
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{
+		Message: "Guest user has been upgraded successfully",
+		Data:    utils.WithTokens(c, pair, map[string]any{"userId": user.ID}),
+	})
+}
+
+// deletes the guests who didnt upgrade within GuestAccountLifetime, it checks every hour until the app stops
+func CleanUpExpiredGuests(users store.UserStore) {
+	for {
+		if err := deleteExpiredGuests(users); err != nil {
+			log.Println("failed to clean up the guest users: " + err.Error())
+		}
+		time.Sleep(time.Hour)
+	}
+}
+
+func deleteExpiredGuests(users store.UserStore) error {
+	guests, err := users.ExpiredGuests(time.Now().Add(-utils.GuestAccountLifetime()))
+	if err != nil {
+		return err
+	}
+
+	for _, guestID := range guests {
+		deleted, err := users.DeleteGuest(guestID)
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			continue
+		}
+
+		if err := sessions.RevokeAllForUser(guestID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/passwords"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	smtpconfigs "github.com/froggy-12/mooshroombase_v2/smtp_configs"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/froggy-12/mooshroombase_v2/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+func LogIn(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
+	var details types.LogInDetails
+	if err := c.BodyParser(&details); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Request body"})
+	}
+
+	if err := validate.Struct(&details); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+
+	user, err := users.FindUserByEmail(details.Email)
+	if err == store.ErrNotFound {
+		if blocked, err := logInBlocked(c, ""); blocked {
+			return err
+		}
+		return failedLogIn(c, "", "", "User Doesnt Exist")
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
+
+	if blocked, err := logInBlocked(c, user.ID); blocked {
+		return err
+	}
+
+	if !passwords.Verify(user.Password, details.Password) {
+		return failedLogIn(c, user.ID, user.Email, "Wrong Password")
+	}
+
+	if err := sessions.ResetFailedLogIns(user.ID); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
+
+	rehashPassword(user.ID, user.Password, details.Password, func(hash string) error {
+		return users.UpdateUser(user.ID, store.UserUpdate{Password: &hash})
+	})
+
+	twoFactor, err := users.FindTwoFactor(user.ID)
+	if err != nil && err != store.ErrNotFound {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
+	if err == nil && twoFactor.Enabled {
+		return utils.TwoFactorChallenge(c, user.ID)
+	}
+
+	if err := users.UpdateUser(user.ID, store.UserUpdate{LastLoggedIn: ptr(time.Now())}); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Something Went Wrong: " + err.Error()})
+	}
+
+	pair, err := sessions.LogIn(c, user.ID)
+	if err != nil {
+		return utils.SessionError(c, err)
+	}
+
+	return utils.LogInResponse(c, user.ID, pair)
+}
+
+// replaces a hash made with another algorithm or older parameters once the password is known, the log in goes on if it fails
+func rehashPassword(userID, hash, password string, store func(hash string) error) {
+	if !passwords.NeedsRehash(hash) {
+		return
+	}
+
+	newHash, err := passwords.Hash(password)
+	if err == nil {
+		err = store(newHash)
+	}
+	if err != nil {
+		utils.DebugLogger("passwords", "failed to rehash the password of "+userID+": "+err.Error())
+	}
+}
+
+// responds with 429 while the account is locked or the backoff of the account or the ip is still running
+func logInBlocked(c *fiber.Ctx, userID string) (bool, error) {
+	retryAfter, err := sessions.CheckLogIn(userID, c.IP())
+	if err == nil {
+		return false, nil
+	}
+	if err == sessions.ErrAccountLocked || err == sessions.ErrTooManyAttempts {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(retryAfter.Seconds())+1))
+		return true, c.Status(http.StatusTooManyRequests).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+	return true, c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+}
+
+// counts the failed attempt and lets the owner know by email when it locked the account
+func failedLogIn(c *fiber.Ctx, userID, email, message string) error {
+	lockedUntil, err := sessions.RecordFailedLogIn(userID, c.IP())
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
+
+	if !lockedUntil.IsZero() {
+		if configs.Configs.SMTPConfigurations.SMTPEnabled {
+			go func() {
+				if err := smtpconfigs.SendAccountLockedEmail(email, lockedUntil); err != nil {
+					utils.DebugLogger("auth", "failed to send account locked email: "+err.Error())
+				}
+			}()
+		}
+		return c.Status(http.StatusTooManyRequests).JSON(types.ErrorResponse{Error: sessions.ErrAccountLocked.Error()})
+	}
+
+	return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: message})
+}
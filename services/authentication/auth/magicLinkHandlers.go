@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/magiclink"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/oauth"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	smtpconfigs "github.com/froggy-12/mooshroombase_v2/smtp_configs"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/froggy-12/mooshroombase_v2/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func RequestMagicLink(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
+	var body types.MagicLinkRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Request body"})
+	}
+
+	if err := validate.Struct(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+
+	// the response must not tell if the email exists so the work happens in the background
+	go func() {
+		if _, err := users.FindUserByEmail(body.Email); err != nil && !configs.Configs.Authentication.MagicLinkSignUp {
+			utils.DebugLogger("auth", "magic link requested for unknown email")
+			return
+		}
+
+		link, err := magiclink.Issue(body.Email)
+		if err != nil {
+			utils.DebugLogger("auth", "failed to create magic link: "+err.Error())
+			return
+		}
+
+		err = smtpconfigs.SendMagicLinkEmail(body.Email, link)
+		if err != nil {
+			utils.DebugLogger("auth", "failed to send magic link email: "+err.Error())
+		}
+	}()
+
+	return c.Status(http.StatusAccepted).JSON(types.HttpSuccessResponse{Message: "If the email can be used to log in a log in link has been sent"})
+}
+
+// opening the link doesnt log in yet so mail scanners which open every link dont use it up
+func ConfirmMagicLink(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if err := magiclink.Check(token); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+	return magiclink.Confirm(c, token)
+}
+
+// the link proves the user owns the email so it is marked as verified
+func VerifyMagicLink(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
+	var body types.MagicLinkVerifyDetails
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Request body"})
+	}
+
+	if err := validate.Struct(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+
+	email, err := magiclink.Verify(body.Token)
+	if err == magiclink.ErrInvalidLink {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
+
+	user, err := users.FindUserByEmail(email)
+	if err == store.ErrNotFound && configs.Configs.Authentication.MagicLinkSignUp {
+		user, err = createMagicLinkUser(users, email)
+	}
+	if err == store.ErrNotFound {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User Doesnt Exist"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
+
+	if err := users.UpdateUser(user.ID, store.UserUpdate{Verified: ptr(true)}); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to update user's verification status: " + err.Error()})
+	}
+
+	twoFactor, err := users.FindTwoFactor(user.ID)
+	if err != nil && err != store.ErrNotFound {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
+	if err == nil && twoFactor.Enabled {
+		return utils.TwoFactorChallenge(c, user.ID)
+	}
+
+	if err := users.UpdateUser(user.ID, store.UserUpdate{LastLoggedIn: ptr(time.Now())}); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Something Went Wrong: " + err.Error()})
+	}
+
+	pair, err := sessions.LogIn(c, user.ID)
+	if err != nil {
+		return utils.SessionError(c, err)
+	}
+
+	return utils.LogInResponse(c, user.ID, pair)
+}
+
+// accounts created by a magic link dont have a password until the user sets one
+func createMagicLinkUser(users store.UserStore, email string) (types.AuthUser, error) {
+	username, err := freeUserName(users, magiclink.UserNameFromEmail(email))
+	if err != nil {
+		return types.AuthUser{}, err
+	}
+
+	newUser := types.AuthUser{
+		ID:             uuid.New().String(),
+		UserName:       username,
+		Email:          email,
+		ProfilePicture: configs.Configs.ExtraConfigurations.DefaultProfilePictureUrl,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		Verified:       true,
+		LastLoggedIn:   types.LastTimeLoggedIn{When: time.Now()},
+		RawData:        []types.RawUserData{},
+		OAuthAccounts:  []types.OAuthAccount{},
+		Roles:          sessions.DefaultRoles(),
+	}
+
+	return newUser, users.CreateUser(newUser)
+}
+
+// usernames are unique so a taken one gets random suffixes until one is free
+func freeUserName(users store.UserStore, username string) (string, error) {
+	candidate := username
+	for attempt := 0; attempt < 5; attempt++ {
+		_, err := users.FindUserByUserName(candidate)
+		if err == store.ErrNotFound {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		candidate = oauth.RandomizeUserName(username)
+	}
+	return "", errors.New("failed to find a free username for " + username)
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/magiclink"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+func mustIssueMagicLink(t *testing.T, email string) string {
+	t.Helper()
+	link, err := magiclink.Issue(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Query().Get("token")
+}
+
+// opening the link only shows the confirmation, the log in happens when it is posted back
+func TestMagicLinkIsOnlyUsedUpByThePost(t *testing.T) {
+	users := setup(t)
+	configs.Configs.Applications.BackEndURlWithDomain = "https://auth.example.com"
+	user := mustCreateUser(t, users, types.AuthUser{Email: "frog@example.com"})
+	token := mustIssueMagicLink(t, user.Email)
+
+	app := fiber.New()
+	app.Get("/verify", ConfirmMagicLink)
+	app.Post("/verify", func(c *fiber.Ctx) error {
+		return VerifyMagicLink(c, users, validate)
+	})
+
+	// a mail scanner and then the user open the link
+	for range 2 {
+		res, _, raw := send(t, app, http.MethodGet, "/verify?token="+url.QueryEscape(token), nil, "")
+		if res.StatusCode != http.StatusOK || !strings.Contains(raw, `method="post"`) {
+			t.Fatalf("GET /verify = %d %s, want the confirmation page", res.StatusCode, raw)
+		}
+	}
+
+	// the button of the page posts the token as a form
+	req := httptest.NewRequest(http.MethodPost, "/verify", strings.NewReader(url.Values{"token": {token}}.Encode()))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+	res, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusAccepted || len(res.Cookies()) == 0 {
+		t.Fatalf("POST /verify = %d with cookies %v, want a log in", res.StatusCode, res.Cookies())
+	}
+
+	if res, _, raw := send(t, app, http.MethodPost, "/verify", map[string]string{"token": token}, ""); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("second POST /verify = %d %s, want the used link rejected", res.StatusCode, raw)
+	}
+	if res, _, raw := send(t, app, http.MethodGet, "/verify?token="+url.QueryEscape(token), nil, ""); res.StatusCode != http.StatusOK {
+		t.Errorf("GET /verify of a used link = %d %s, the page is shown until the link expires", res.StatusCode, raw)
+	}
+	if res, _, raw := send(t, app, http.MethodGet, "/verify?token=invalid", nil, ""); res.StatusCode != http.StatusBadRequest {
+		t.Errorf("GET /verify of an invalid link = %d %s, want 400", res.StatusCode, raw)
+	}
+}
+
+func TestCreateMagicLinkUser(t *testing.T) {
+	users := setup(t)
+	mustCreateUser(t, users, types.AuthUser{UserName: "frog", Email: "frog@other.example.com"})
+
+	user, err := createMagicLinkUser(users, "frog@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.UserName == "frog" || !strings.HasPrefix(user.UserName, "frog-") {
+		t.Errorf("createMagicLinkUser() username = %q, want frog with a suffix", user.UserName)
+	}
+
+	user, err = createMagicLinkUser(users, "toad@example.com")
+	if err != nil || user.UserName != "toad" {
+		t.Errorf("createMagicLinkUser() = %q, %v, want the free username toad", user.UserName, err)
+	}
+}
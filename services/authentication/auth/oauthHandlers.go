@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/oauth"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/froggy-12/mooshroombase_v2/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func GoogleOAuthCallback(c *fiber.Ctx, users store.UserStore) error {
+	profile, intent, err := oauth.GoogleUserProfile(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Google authorization failed: " + err.Error()})
+	}
+
+	if intent == oauth.LinkIntent {
+		return linkOAuthProfile(c, users, profile)
+	}
+	return logInWithOAuthProfile(c, users, profile)
+}
+
+func GithubOAuthCallback(c *fiber.Ctx, users store.UserStore) error {
+	profile, intent, err := oauth.GithubUserProfile(c)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Github authorization failed: " + err.Error()})
+	}
+
+	if intent == oauth.LinkIntent {
+		return linkOAuthProfile(c, users, profile)
+	}
+	return logInWithOAuthProfile(c, users, profile)
+}
+
+func OIDCCallback(c *fiber.Ctx, users store.UserStore, providerConfig configs.OIDCProvider) error {
+	profile, intent, err := oauth.OIDCUserProfile(c, providerConfig)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: providerConfig.Name + " authorization failed: " + err.Error()})
+	}
+
+	if intent == oauth.LinkIntent {
+		return linkOAuthProfile(c, users, profile)
+	}
+	return logInWithOAuthProfile(c, users, profile)
+}
+
+func UnlinkOAuthProvider(c *fiber.Ctx, users store.UserStore, provider string) error {
+	userId, err := utils.CurrentUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "Something went Wrong: " + err.Error()})
+	}
+
+	user, err := users.FindUserByID(userId)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User Not Found: " + err.Error()})
+	}
+
+	linked := false
+	for _, account := range user.OAuthAccounts {
+		if account.Provider == provider {
+			linked = true
+			break
+		}
+	}
+	if !linked {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "No " + provider + " account is linked"})
+	}
+
+	// without a password or another provider the user would not be able to log in again
+	if user.Password == "" && len(user.OAuthAccounts) == 1 {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "This is the only way to log in to this account, set a password before unlinking"})
+	}
+
+	if err := users.UnlinkOAuthAccount(user.ID, provider); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to unlink account: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "Account has been unlinked successfully"})
+}
+
+func linkOAuthProfile(c *fiber.Ctx, users store.UserStore, profile types.OAuthUserProfile) error {
+	userID, err := oauth.LoggedInUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+
+	owner, err := users.FindUserByOAuthAccount(profile.Provider, profile.ProviderUserID)
+	if err == nil {
+		if owner.ID == userID {
+			return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "This account is already linked"})
+		}
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "This account is already linked with another user"})
+	}
+	if err != store.ErrNotFound {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
+
+	user, err := users.FindUserByID(userID)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User Not Found: " + err.Error()})
+	}
+	for _, account := range user.OAuthAccounts {
+		if account.Provider == profile.Provider {
+			return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Another " + profile.Provider + " account is already linked, unlink it first"})
+		}
+	}
+
+	if user.Guest {
+		return upgradeGuestWithOAuthProfile(c, users, user, profile)
+	}
+
+	if err := users.LinkOAuthAccount(user.ID, oauthAccount(profile)); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to link account: " + err.Error()})
+	}
+
+	return oauth.Respond(c, "Account has been linked successfully", map[string]any{"provider": profile.Provider})
+}
+
+// a guest linking a provider becomes a full account with the details of the provider profile, the id stays the same
+func upgradeGuestWithOAuthProfile(c *fiber.Ctx, users store.UserStore, user types.AuthUser, profile types.OAuthUserProfile) error {
+	email := profile.Email
+	if email == "" {
+		email = user.Email
+	} else if _, err := users.FindUserByEmail(email); err == nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "An account with this email already exists please log in with it instead"})
+	}
+
+	username := oauth.UserNameFromProfile(profile)
+	if _, err := users.FindUserByUserName(username); err == nil {
+		username = oauth.RandomizeUserName(username)
+	}
+
+	profilePicture := profile.ProfilePicture
+	if profilePicture == "" {
+		profilePicture = user.ProfilePicture
+	}
+
+	if err := users.LinkOAuthAccount(user.ID, oauthAccount(profile)); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to link account: " + err.Error()})
+	}
+
+	err := users.UpgradeGuest(user.ID, store.UserUpdate{
+		UserName:       &username,
+		FirstName:      &profile.FirstName,
+		LastName:       &profile.LastName,
+		Email:          &email,
+		ProfilePicture: &profilePicture,
+		Verified:       &profile.EmailVerified,
+		Roles:          sessions.DefaultRoles(),
+	})
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to upgrade the guest user: " + err.Error()})
+	}
+
+	if _, err := utils.ReplaceSession(c, user.ID); err != nil {
+		return utils.SessionError(c, err)
+	}
+
+	return oauth.Respond(c, "Guest user has been upgraded successfully", map[string]any{"provider": profile.Provider, "userId": user.ID})
+}
+
+func logInWithOAuthProfile(c *fiber.Ctx, users store.UserStore, profile types.OAuthUserProfile) error {
+	userID, err := findOrCreateOAuthUser(users, profile)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+
+	twoFactor, err := users.FindTwoFactor(userID)
+	if err != nil && err != store.ErrNotFound {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
+	if err == nil && twoFactor.Enabled {
+		return oauth.TwoFactorChallenge(c, userID)
+	}
+
+	if err := users.UpdateUser(userID, store.UserUpdate{LastLoggedIn: ptr(time.Now())}); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something Went Wrong: " + err.Error()})
+	}
+
+	return oauth.FinishLogIn(c, userID)
+}
+
+// finds the user linked with the provider account, links an existing verified user with the same email or creates a new one
+func findOrCreateOAuthUser(users store.UserStore, profile types.OAuthUserProfile) (string, error) {
+	user, err := users.FindUserByOAuthAccount(profile.Provider, profile.ProviderUserID)
+	if err == nil {
+		return user.ID, nil
+	}
+	if err != store.ErrNotFound {
+		return "", errors.New("something went wrong: " + err.Error())
+	}
+
+	// anyone can sign up with an email they dont own, so only an account which proved it owns the email is linked
+	// otherwise the provider account would log in to whatever the squatter set up
+	user, err = users.FindUserByEmail(profile.Email)
+	if err == nil {
+		if !profile.EmailVerified || !user.Verified {
+			return "", errors.New("an account with this email already exists please log in to it and link " + profile.Provider + " from there")
+		}
+		if err := users.LinkOAuthAccount(user.ID, oauthAccount(profile)); err != nil {
+			return "", errors.New("failed to link account: " + err.Error())
+		}
+		return user.ID, nil
+	}
+	if err != store.ErrNotFound {
+		return "", errors.New("something went wrong: " + err.Error())
+	}
+
+	username := oauth.UserNameFromProfile(profile)
+	if _, err := users.FindUserByUserName(username); err == nil {
+		username = oauth.RandomizeUserName(username)
+	}
+
+	profilePicture := profile.ProfilePicture
+	if profilePicture == "" {
+		profilePicture = configs.Configs.ExtraConfigurations.DefaultProfilePictureUrl
+	}
+
+	// oauth users dont have a password so password login wont ever match for them
+	newUser := types.AuthUser{
+		ID:             uuid.New().String(),
+		UserName:       username,
+		FirstName:      profile.FirstName,
+		LastName:       profile.LastName,
+		Email:          profile.Email,
+		ProfilePicture: profilePicture,
+		Verified:       profile.EmailVerified,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		LastLoggedIn:   types.LastTimeLoggedIn{When: time.Now()},
+		RawData:        []types.RawUserData{},
+		OAuthAccounts:  []types.OAuthAccount{oauthAccount(profile)},
+		Roles:          sessions.DefaultRoles(),
+	}
+
+	if err := users.CreateUser(newUser); err != nil {
+		return "", errors.New("failed to create new user into the database: " + err.Error())
+	}
+
+	return newUser.ID, nil
+}
+
+func oauthAccount(profile types.OAuthUserProfile) types.OAuthAccount {
+	return types.OAuthAccount{
+		Provider:       profile.Provider,
+		ProviderUserID: profile.ProviderUserID,
+		Email:          profile.Email,
+		LinkedAt:       time.Now(),
+	}
+}
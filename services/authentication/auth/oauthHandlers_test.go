@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/froggy-12/mooshroombase_v2/types"
+)
+
+func TestFindOrCreateOAuthUser(t *testing.T) {
+	profile := types.OAuthUserProfile{Provider: "github", ProviderUserID: "42", Email: "frog@example.com", EmailVerified: true, FirstName: "Frog"}
+
+	tests := []struct {
+		name string
+		// the local account with the email of the profile, nil when there is none
+		local       *types.AuthUser
+		unverified  bool
+		wantErr     bool
+		wantLinked  bool
+		wantCreated bool
+	}{
+		{
+			name:        "new user",
+			wantCreated: true,
+		},
+		{
+			name:       "verified account with the same email",
+			local:      &types.AuthUser{Email: profile.Email, Verified: true},
+			wantLinked: true,
+		},
+		{
+			// someone could have signed up with the email before its owner logs in with the provider
+			name:    "unverified account with the same email",
+			local:   &types.AuthUser{Email: profile.Email, Password: "squatter password"},
+			wantErr: true,
+		},
+		{
+			name:       "email not verified by the provider",
+			local:      &types.AuthUser{Email: profile.Email, Verified: true},
+			unverified: true,
+			wantErr:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			users := setup(t)
+			var local types.AuthUser
+			if test.local != nil {
+				local = mustCreateUser(t, users, *test.local)
+			}
+			profile := profile
+			profile.EmailVerified = !test.unverified
+
+			userID, err := findOrCreateOAuthUser(users, profile)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("findOrCreateOAuthUser() = %q, %v, want error %v", userID, err, test.wantErr)
+			}
+
+			owner, findErr := users.FindUserByOAuthAccount(profile.Provider, profile.ProviderUserID)
+			switch {
+			case test.wantErr:
+				if findErr != store.ErrNotFound {
+					t.Errorf("provider account was linked to %q, %v", owner.ID, findErr)
+				}
+				if stored, err := users.FindUserByID(local.ID); err != nil || stored.Verified != local.Verified {
+					t.Errorf("local account changed to verified %v, %v", stored.Verified, err)
+				}
+			case test.wantLinked:
+				if userID != local.ID || owner.ID != local.ID {
+					t.Errorf("findOrCreateOAuthUser() = %q, linked to %q, want the local account %q", userID, owner.ID, local.ID)
+				}
+			case test.wantCreated:
+				if findErr != nil || owner.ID != userID || owner.Email != profile.Email {
+					t.Errorf("created user %q is linked to %q, %v", userID, owner.ID, findErr)
+				}
+			}
+
+			// the second log in finds the linked account
+			if !test.wantErr {
+				if again, err := findOrCreateOAuthUser(users, profile); err != nil || again != userID {
+					t.Errorf("second findOrCreateOAuthUser() = %q, %v, want %q", again, err, userID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/passkeys"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/froggy-12/mooshroombase_v2/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+func loadPasskeyUser(users store.UserStore, userID string) (passkeys.User, error) {
+	user, err := users.FindUserByID(userID)
+	if err != nil {
+		return passkeys.User{}, err
+	}
+
+	stored, err := users.FindPasskeys(user.ID)
+	if err != nil {
+		return passkeys.User{}, err
+	}
+
+	return passkeys.NewUser(user.ID, user.Email, user.UserName, stored), nil
+}
+
+func BeginPasskeyRegistration(c *fiber.Ctx, users store.UserStore) error {
+	userId, err := utils.CurrentUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "Something went Wrong: " + err.Error()})
+	}
+
+	user, err := loadPasskeyUser(users, userId)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User Not Found: " + err.Error()})
+	}
+
+	return passkeys.BeginRegistration(c, user)
+}
+
+// the body is the credential returned by the browser, the passkey name can be given with ?name=
+func FinishPasskeyRegistration(c *fiber.Ctx, users store.UserStore) error {
+	userId, err := utils.CurrentUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "Something went Wrong: " + err.Error()})
+	}
+
+	user, err := loadPasskeyUser(users, userId)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User Not Found: " + err.Error()})
+	}
+
+	credential, err := passkeys.FinishRegistration(c, user)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Passkey registration failed: " + err.Error()})
+	}
+
+	passkey := passkeys.FromCredential(user.ID, c.Query("name", "Passkey"), credential)
+	if err := users.CreatePasskey(passkey); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to store the passkey: " + err.Error()})
+	}
+
+	return c.Status(http.StatusCreated).JSON(types.HttpSuccessResponse{Message: "Passkey has been registered successfully", Data: map[string]any{"passkey": passkey}})
+}
+
+func ListPasskeys(c *fiber.Ctx, users store.UserStore) error {
+	userId, err := utils.CurrentUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "Something went Wrong: " + err.Error()})
+	}
+
+	stored, err := users.FindPasskeys(userId)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Data: map[string]any{"passkeys": stored}})
+}
+
+func DeletePasskey(c *fiber.Ctx, users store.UserStore) error {
+	userId, err := utils.CurrentUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "Something went Wrong: " + err.Error()})
+	}
+
+	err = users.DeletePasskey(userId, c.Params("id"))
+	if err == store.ErrNotFound {
+		return c.Status(http.StatusNotFound).JSON(types.ErrorResponse{Error: "Passkey not found"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to remove the passkey: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "Passkey has been removed successfully"})
+}
+
+func FinishPasskeyLogIn(c *fiber.Ctx, users store.UserStore) error {
+	user, credential, err := passkeys.FinishLogIn(c, func(userID string) (passkeys.User, error) {
+		return loadPasskeyUser(users, userID)
+	})
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "Passkey log in failed: " + err.Error()})
+	}
+
+	err = users.UpdatePasskeyUsage(user.ID, passkeys.CredentialID(credential), credential.Authenticator.SignCount, credential.Flags.BackupState)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something Went Wrong: " + err.Error()})
+	}
+
+	if err := users.UpdateUser(user.ID, store.UserUpdate{LastLoggedIn: ptr(time.Now())}); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Something Went Wrong: " + err.Error()})
+	}
+
+	pair, err := sessions.LogIn(c, user.ID)
+	if err != nil {
+		return utils.SessionError(c, err)
+	}
+
+	return utils.LogInResponse(c, user.ID, pair)
+}
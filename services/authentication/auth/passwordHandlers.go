@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/passwords"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	smtpconfigs "github.com/froggy-12/mooshroombase_v2/smtp_configs"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/froggy-12/mooshroombase_v2/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+func RequestPasswordReset(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
+	if !configs.Configs.SMTPConfigurations.SMTPEnabled {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "SMTP is not configured or turned off please check again and restart the app"})
+	}
+
+	var body types.PasswordResetRequest
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Request body"})
+	}
+
+	if err := validate.Struct(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+
+	// the response must not tell if the email exists so the work happens in the background
+	go func() {
+		user, err := users.FindUserByEmail(body.Email)
+		if err != nil {
+			utils.DebugLogger("auth", "password reset requested for unknown email")
+			return
+		}
+
+		token, err := utils.GenerateRandomToken(32)
+		if err != nil {
+			utils.DebugLogger("auth", "failed to generate password reset token: "+err.Error())
+			return
+		}
+
+		reset := types.PasswordReset{
+			TokenHash: utils.HashToken(token),
+			UserID:    user.ID,
+			ExpiresAt: time.Now().Add(time.Minute * time.Duration(configs.Configs.Authentication.PasswordResetTokenExpiration)),
+			CreatedAt: time.Now(),
+		}
+
+		if err := users.CreatePasswordReset(reset); err != nil {
+			utils.DebugLogger("auth", "failed to store password reset token: "+err.Error())
+			return
+		}
+
+		err = smtpconfigs.SendPasswordResetEmail(user.Email, token)
+		if err != nil {
+			utils.DebugLogger("auth", "failed to send password reset email: "+err.Error())
+		}
+	}()
+
+	return c.Status(http.StatusAccepted).JSON(types.HttpSuccessResponse{Message: "If an account with this email exists a password reset email has been sent"})
+}
+
+func ResetPassword(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
+	var body types.ResetPasswordDetails
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Request body"})
+	}
+
+	if err := validate.Struct(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+
+	// taking the token removes it so it can only be used once
+	reset, err := users.TakePasswordReset(utils.HashToken(body.Token))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid or expired token"})
+	}
+
+	if time.Now().After(reset.ExpiresAt) {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid or expired token"})
+	}
+
+	hashedPassword, err := passwords.Hash(body.NewPassword)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to Hash the password: " + err.Error()})
+	}
+
+	err = users.UpdateUser(reset.UserID, store.UserUpdate{Password: &hashedPassword})
+	if err == store.ErrNotFound {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User not found"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to update password: " + err.Error()})
+	}
+
+	if err := users.DeletePasswordResets(reset.UserID); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to remove old reset tokens: " + err.Error()})
+	}
+
+	if err := sessions.RevokeAllForUser(reset.UserID); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Password has been changed but failed to log out other sessions: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "Password has been reset successfully please log in again"})
+}
+
+func ChangePassword(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
+	userId, err := utils.CurrentUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "Something went Wrong: " + err.Error()})
+	}
+
+	var body types.ChangePasswordDetails
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Request body"})
+	}
+
+	if err := validate.Struct(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+
+	user, err := users.FindUserByID(userId)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User Not Found: " + err.Error()})
+	}
+	if user.Guest {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Guest users have to upgrade their account to set a password"})
+	}
+
+	// an access token alone must not be enough to take over an account so users without a password set their first one
+	// through the password reset email like everyone who forgot theirs
+	if user.Password == "" {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "This account doesnt have a password yet, request a password reset email to set one"})
+	}
+	if body.CurrentPassword == "" {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Current password is required"})
+	}
+	if !passwords.Verify(user.Password, body.CurrentPassword) {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Wrong Password"})
+	}
+
+	hashedPassword, err := passwords.Hash(body.NewPassword)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to Hash the password: " + err.Error()})
+	}
+
+	if err := users.UpdateUser(user.ID, store.UserUpdate{Password: &hashedPassword}); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to update password: " + err.Error()})
+	}
+
+	// every session is logged out and the current device gets a new one
+	if err := sessions.RevokeAllForUser(user.ID); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Password has been changed but failed to log out other sessions: " + err.Error()})
+	}
+
+	pair, err := sessions.LogIn(c, user.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Password has been changed but failed to create a new session: " + err.Error()})
+	}
+
+	return c.Status(http.StatusAccepted).JSON(types.HttpSuccessResponse{Message: "Password Has been Updated", Data: utils.WithTokens(c, pair, map[string]any{})})
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/passwords"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestChangePassword(t *testing.T) {
+	tests := []struct {
+		name        string
+		password    string
+		body        map[string]string
+		wantStatus  int
+		wantError   string
+		wantChanged bool
+	}{
+		{
+			name:        "with the current password",
+			password:    "old password",
+			body:        map[string]string{"currentPassword": "old password", "newPassword": "new password"},
+			wantStatus:  http.StatusAccepted,
+			wantChanged: true,
+		},
+		{
+			name:       "with a wrong current password",
+			password:   "old password",
+			body:       map[string]string{"currentPassword": "guessed password", "newPassword": "new password"},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "Wrong Password",
+		},
+		{
+			name:       "without the current password",
+			password:   "old password",
+			body:       map[string]string{"newPassword": "new password"},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "Current password is required",
+		},
+		{
+			// accounts created by a provider or a magic link set their first password through the reset email
+			name:       "account without a password",
+			body:       map[string]string{"newPassword": "new password"},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "password reset email",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			users := setup(t)
+			user := mustCreateUser(t, users, types.AuthUser{Password: test.password})
+			app := protected(http.MethodPut, "/password", func(c *fiber.Ctx) error {
+				return ChangePassword(c, users, validate)
+			})
+
+			res, _, raw := send(t, app, http.MethodPut, "/password", test.body, mustLogIn(t, user.ID).AccessToken)
+			if res.StatusCode != test.wantStatus || !strings.Contains(raw, test.wantError) {
+				t.Fatalf("ChangePassword() = %d %s, want %d with %q", res.StatusCode, raw, test.wantStatus, test.wantError)
+			}
+
+			stored, err := users.FindUserByID(user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed := passwords.Verify(stored.Password, "new password"); changed != test.wantChanged {
+				t.Errorf("password changed = %v, want %v", changed, test.wantChanged)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// replaces the roles of a user, they end up in the user's access tokens after the next refresh
+func SetUserRoles(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
+	var body types.UserRolesDetails
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Request body"})
+	}
+
+	if err := validate.Struct(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+
+	roles := []string{}
+	for _, role := range body.Roles {
+		if !sessions.IsKnownRole(role) {
+			return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Unknown role: " + role})
+		}
+		if !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+
+	err := users.UpdateUser(c.Params("id"), store.UserUpdate{Roles: roles})
+	if err == store.ErrNotFound {
+		return c.Status(http.StatusNotFound).JSON(types.ErrorResponse{Error: "User Not Found"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to update the roles: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "Roles have been updated successfully", Data: map[string]any{"roles": roles}})
+}
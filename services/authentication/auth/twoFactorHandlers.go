@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/twofactor"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/froggy-12/mooshroombase_v2/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+// creates a new secret which only gets enabled after it has been confirmed with a code
+func EnrollTwoFactor(c *fiber.Ctx, users store.UserStore) error {
+	userId, err := utils.CurrentUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "Something went Wrong: " + err.Error()})
+	}
+
+	user, err := users.FindUserByID(userId)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User Not Found: " + err.Error()})
+	}
+
+	twoFactor, err := users.FindTwoFactor(user.ID)
+	if err == nil && twoFactor.Enabled {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Two factor authentication is already enabled"})
+	}
+	if err != nil && err != store.ErrNotFound {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
+
+	key, err := twofactor.NewKey(user.Email)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to generate the secret: " + err.Error()})
+	}
+
+	data, err := twofactor.EnrollmentData(key)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to generate the qr code: " + err.Error()})
+	}
+
+	if err := users.SaveTwoFactorSecret(user.ID, key.Secret()); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to store the secret: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "Scan the qr code and confirm it with a code from your authenticator app", Data: data})
+}
+
+// enables two factor and hands out the recovery codes, they are never shown again
+func ConfirmTwoFactor(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
+	userId, err := utils.CurrentUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "Something went Wrong: " + err.Error()})
+	}
+
+	var body types.TwoFactorCode
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Request body"})
+	}
+
+	if err := validate.Struct(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+
+	twoFactor, err := users.FindTwoFactor(userId)
+	if err == store.ErrNotFound {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Two factor enrollment has not been started"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
+	if twoFactor.Enabled {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Two factor authentication is already enabled"})
+	}
+
+	valid, err := twofactor.CheckCode(userId, twoFactor.Secret, body.Code)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
+	if !valid {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid code"})
+	}
+
+	codes, hashes, err := twofactor.GenerateRecoveryCodes()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to generate recovery codes: " + err.Error()})
+	}
+
+	if err := users.EnableTwoFactor(userId, hashes); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to enable two factor authentication: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "Two factor authentication has been enabled, store the recovery codes somewhere safe", Data: map[string]any{"recoveryCodes": codes}})
+}
+
+func DisableTwoFactor(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
+	userId, err := utils.CurrentUserID(c)
+	if err != nil {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "Something went Wrong: " + err.Error()})
+	}
+
+	var body types.TwoFactorCode
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Request body"})
+	}
+
+	if err := validate.Struct(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+
+	twoFactor, err := users.FindTwoFactor(userId)
+	if err == store.ErrNotFound || (err == nil && !twoFactor.Enabled) {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Two factor authentication is not enabled"})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
+
+	valid, err := verifyTwoFactorCode(users, twoFactor, body.Code)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
+	if !valid {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid code"})
+	}
+
+	if err := users.DeleteTwoFactor(userId); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to disable two factor authentication: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "Two factor authentication has been disabled"})
+}
+
+// second step of the log in for users with two factor enabled
+func VerifyTwoFactorLogIn(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
+	var body types.TwoFactorLogInDetails
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Request body"})
+	}
+
+	if err := validate.Struct(&body); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+
+	userId, err := sessions.MFAChallengeUser(body.MFAToken)
+	if err == sessions.ErrInvalidMFAToken {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
+
+	twoFactor, err := users.FindTwoFactor(userId)
+	if err != nil || !twoFactor.Enabled {
+		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "Two factor authentication is not enabled please log in again"})
+	}
+
+	valid, err := verifyTwoFactorCode(users, twoFactor, body.Code)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
+	if !valid {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid code"})
+	}
+
+	if err := sessions.DeleteMFAChallenge(body.MFAToken); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
+	}
+
+	if err := users.UpdateUser(userId, store.UserUpdate{LastLoggedIn: ptr(time.Now())}); err != nil {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Something Went Wrong: " + err.Error()})
+	}
+
+	pair, err := sessions.LogIn(c, userId)
+	if err != nil {
+		return utils.SessionError(c, err)
+	}
+
+	return utils.LogInResponse(c, userId, pair)
+}
+
+// accepts a totp code or one of the recovery codes, a recovery code is removed when it is used
+func verifyTwoFactorCode(users store.UserStore, twoFactor types.TwoFactor, code string) (bool, error) {
+	valid, err := twofactor.CheckCode(twoFactor.UserID, twoFactor.Secret, code)
+	if err != nil || valid {
+		return valid, err
+	}
+
+	return users.UseRecoveryCode(twoFactor.UserID, twofactor.HashRecoveryCode(code))
+}
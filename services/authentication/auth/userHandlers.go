@@ -1,75 +1,82 @@
-package mongoauth
+package auth
 
 import (
 	"context"
 	"net/http"
-	"time"
 
-	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/passwords"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/profilefields"
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
 	"github.com/froggy-12/mooshroombase_v2/types"
 	"github.com/froggy-12/mooshroombase_v2/utils"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"golang.org/x/crypto/bcrypt"
 )
 
-func Get_User(c *fiber.Ctx, mongoClient *mongo.Client) error {
-	token := c.Cookies("jwtToken")
-	userId, err := utils.ExtractJWTToken(token, configs.Configs.HttpConfigurations.JWTSecret)
+func Get_User(c *fiber.Ctx, users store.UserStore) error {
+	userId, err := utils.CurrentUserID(c)
 	if err != nil {
 		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "Something went Wrong: " + err.Error()})
 	}
 
-	coll := mongoClient.Database("mooshroombase").Collection("users")
-	user, err := utils.FindUserFromMongoDBUsingID(userId, coll)
-
+	user, err := users.FindUserByID(userId)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something Went Wrong maybe user not found: " + err.Error()})
 	}
 
+	lockStatus, err := sessions.LockStatusOf(user.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to load the lock status: " + err.Error()})
+	}
+
+	roles, err := sessions.RolesOf(user.ID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to load the roles: " + err.Error()})
+	}
+
 	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{
 		Message: "User has been Found successfully",
-		Data:    map[string]any{"user": user},
+		Data:    map[string]any{"user": user, "lockStatus": lockStatus, "roles": roles, "guest": user.Guest},
 	})
 }
 
-func UpdateUser(c *fiber.Ctx, mongoClient *mongo.Client) error {
-	token := c.Cookies("jwtToken")
-	userId, err := utils.ExtractJWTToken(token, configs.Configs.HttpConfigurations.JWTSecret)
+func UpdateUser(c *fiber.Ctx, users store.UserStore) error {
+	userId, err := utils.CurrentUserID(c)
 	if err != nil {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
 	}
 
-	var UpdatedUser types.UpdateMongoUser
-	if err := c.BodyParser(&UpdatedUser); err != nil {
+	var details types.UpdateUserDetails
+	if err := c.BodyParser(&details); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
 	}
 
-	coll := mongoClient.Database("mooshroombase").Collection("users")
-	user, err := utils.FindUserFromMongoDBUsingID(userId, coll)
-
-	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User Not Found: " + err.Error()})
+	update := store.UserUpdate{}
+	if details.FirstName != "" {
+		update.FirstName = &details.FirstName
 	}
-
-	if UpdatedUser.FirstName == "" {
-		UpdatedUser.FirstName = user.FirstName
+	if details.LastName != "" {
+		update.LastName = &details.LastName
 	}
-	if UpdatedUser.LastName == "" {
-		UpdatedUser.LastName = user.LastName
+	if details.ProfilePicture != "" {
+		update.ProfilePicture = &details.ProfilePicture
 	}
-	if UpdatedUser.ProfilePicture == "" {
-		UpdatedUser.ProfilePicture = user.ProfilePicture
+	if len(details.RawData) > 0 {
+		update.RawData = details.RawData
 	}
-	if len(UpdatedUser.RawData) == 0 {
-		UpdatedUser.RawData = user.RawData
+	if len(details.ProfileFields) > 0 {
+		if err := profilefields.Check(details.ProfileFields); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid profile fields: " + err.Error()})
+		}
+		update.ProfileFields = details.ProfileFields
 	}
 
-	_, err = coll.UpdateOne(context.Background(), bson.M{"id": user.ID}, bson.M{"$set": bson.M{"firstName": UpdatedUser.FirstName, "lastName": UpdatedUser.LastName, "profilePicture": UpdatedUser.ProfilePicture, "rawData": UpdatedUser.RawData, "updatedAt": time.Now()}})
-
+	err = users.UpdateUser(userId, update)
+	if err == store.ErrNotFound {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User Not Found: " + err.Error()})
+	}
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to Update User " + err.Error()})
 	}
@@ -77,7 +84,7 @@ func UpdateUser(c *fiber.Ctx, mongoClient *mongo.Client) error {
 	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "User Has been Updated Successfully"})
 }
 
-func UpdateUserName(c *fiber.Ctx, mongoClient *mongo.Client, validator validator.Validate) error {
+func UpdateUserName(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
 	var body struct {
 		UserName    string `json:"username" validate:"required"`
 		NewUserName string `json:"newUserName" validate:"required"`
@@ -87,48 +94,41 @@ func UpdateUserName(c *fiber.Ctx, mongoClient *mongo.Client, validator validator
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Request Body"})
 	}
 
-	if err := validator.Struct(&body); err != nil {
+	if err := validate.Struct(&body); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Request Body: " + err.Error()})
 	}
 
-	coll := mongoClient.Database("mooshroombase").Collection("users")
-	user, err := utils.FindUserFromMongoDBUsingUsername(body.UserName, coll)
+	user, err := users.FindUserByUserName(body.UserName)
 	if err != nil {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User Not Found: " + err.Error()})
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.Password))
-	if err != nil {
+	if !passwords.Verify(user.Password, body.Password) {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Wrong Password or Username"})
 	}
 
-	_, err = coll.UpdateOne(context.Background(), bson.M{"id": user.ID}, bson.M{"$set": bson.M{"username": body.NewUserName, "updatedAt": time.Now()}})
-	if err != nil {
+	if err := users.UpdateUser(user.ID, store.UserUpdate{UserName: &body.NewUserName}); err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong failed to update username: " + err.Error()})
 	}
 
 	return c.Status(http.StatusAccepted).JSON(types.HttpSuccessResponse{Message: "Username Has been Updated"})
 }
 
-func AppendRawData(c *fiber.Ctx, mongoClient *mongo.Client) error {
-	token := c.Cookies("jwtToken")
-	userId, err := utils.ExtractJWTToken(token, configs.Configs.HttpConfigurations.JWTSecret)
+func AppendRawData(c *fiber.Ctx, users store.UserStore) error {
+	userId, err := utils.CurrentUserID(c)
 	if err != nil {
 		return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "Something went Wrong: " + err.Error()})
 	}
 
-	coll := mongoClient.Database("mooshroombase").Collection("users")
-
-	var body types.RawUserData
 	var requestBody map[string]any
 	if err := c.BodyParser(&requestBody); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
 	}
 
-	body.Data = requestBody
-
-	_, err = coll.UpdateOne(context.Background(), bson.M{"id": userId}, bson.M{"$push": bson.M{"rawData": body}, "$set": bson.M{"updatedAt": time.Now()}})
-
+	err = users.AppendRawData(userId, types.RawUserData{Data: requestBody})
+	if err == store.ErrNotFound {
+		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User Not Found: " + err.Error()})
+	}
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to append raw data: " + err.Error()})
 	}
@@ -136,7 +136,7 @@ func AppendRawData(c *fiber.Ctx, mongoClient *mongo.Client) error {
 	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Message: "Raw data appended successfully"})
 }
 
-func ChangeEmail(c *fiber.Ctx, mongoClient *mongo.Client, validator validator.Validate) error {
+func ChangeEmail(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
 	var body struct {
 		Email    string `json:"email" validate:"required,email"`
 		NewEmail string `json:"newEmail" validate:"required,email"`
@@ -147,30 +147,27 @@ func ChangeEmail(c *fiber.Ctx, mongoClient *mongo.Client, validator validator.Va
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid request body: " + err.Error()})
 	}
 
-	if err := validator.Struct(&body); err != nil {
+	if err := validate.Struct(&body); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid request body: " + err.Error()})
 	}
-	coll := mongoClient.Database("mooshroombase").Collection("users")
 
-	user, err := utils.FindUserFromMongoDBUsingEmail(body.Email, coll)
+	user, err := users.FindUserByEmail(body.Email)
 	if err != nil {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User Not Found: " + err.Error()})
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.Password))
-	if err != nil {
+	if !passwords.Verify(user.Password, body.Password) {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Wrong Password or email"})
 	}
-	_, err = coll.UpdateOne(context.Background(), bson.M{"id": user.ID}, bson.M{"$set": bson.M{"email": body.NewEmail, "verified": false, "updatedAt": time.Now()}})
-	if err != nil {
+
+	if err := users.UpdateUser(user.ID, store.UserUpdate{Email: &body.NewEmail, Verified: ptr(false)}); err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong failed to update email: " + err.Error()})
 	}
 
 	return c.Status(http.StatusAccepted).JSON(types.HttpSuccessResponse{Message: "Email Has been Updated"})
 }
 
-func DeleteUser(c *fiber.Ctx, mongoClient *mongo.Client, validator validator.Validate) error {
-	token := c.Cookies("jwtToken")
-	userId, err := utils.ExtractJWTToken(token, configs.Configs.HttpConfigurations.JWTSecret)
+func DeleteUser(c *fiber.Ctx, users store.UserStore, validate validator.Validate) error {
+	userId, err := utils.CurrentUserID(c)
 	if err != nil {
 		return c.Status(http.StatusBadRequest).JSON("something went wrong: " + err.Error())
 	}
@@ -184,66 +181,62 @@ func DeleteUser(c *fiber.Ctx, mongoClient *mongo.Client, validator validator.Val
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid request body: " + err.Error()})
 	}
 
-	if err := validator.Struct(&body); err != nil {
+	if err := validate.Struct(&body); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid request body: " + err.Error()})
 	}
-	coll := mongoClient.Database("mooshroombase").Collection("users")
-	user, err := utils.FindUserFromMongoDBUsingID(userId, coll)
 
+	user, err := users.FindUserByID(userId)
 	if err != nil {
 		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "User not found: " + err.Error()})
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(body.Password))
-	if err != nil {
+	if !passwords.Verify(user.Password, body.Password) {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Wrong Password or email"})
 	}
 
-	_, err = coll.DeleteOne(context.Background(), bson.M{"email": user.Email})
-
-	if err != nil {
+	if err := users.DeleteUser(user.ID); err != nil {
 		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Failed to delete user: " + err.Error()})
 	}
 
+	// tokens issued to other devices stay valid until they expire otherwise
+	if err := sessions.RevokeAllForUser(user.ID); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "User has been deleted but failed to log out the sessions: " + err.Error()})
+	}
+	sessions.ClearTokenCookies(c)
+
 	return c.Status(http.StatusAccepted).JSON(types.HttpSuccessResponse{Message: "User has been deleted successfully"})
 }
 
-func GetRealTimeUserData(c *websocket.Conn, mongoClient *mongo.Client) {
-	userId := c.Query("user_id")
+// the user comes from the access token checked by the jwt middleware before the upgrade, user_id can only name that user
+func GetRealTimeUserData(c *websocket.Conn, users store.UserStore) {
+	userId, _ := c.Locals("userId").(string)
 
 	if userId == "" {
-		c.WriteJSON(types.ErrorResponse{Error: "User ID is required"})
+		c.WriteJSON(types.ErrorResponse{Error: "User is not authorised please log in"})
 		c.Close()
 		return
 	}
-	coll := mongoClient.Database("mooshroombase").Collection("users")
-
-	user, err := utils.FindUserFromMongoDBUsingID(userId, coll)
-
-	if err != nil {
-		c.WriteJSON(types.ErrorResponse{Error: "User Not Found!"})
+	if queryUserId := c.Query("user_id"); queryUserId != "" && queryUserId != userId {
+		c.WriteJSON(types.ErrorResponse{Error: "User can only watch their own data"})
 		c.Close()
 		return
 	}
 
-	c.WriteJSON(types.HttpSuccessResponse{Data: map[string]any{"userData": user}})
+	// the first message carries the user as userData, every change after it as user
+	key := "userData"
+	err := users.WatchUser(context.TODO(), userId, func(user types.AuthUser) error {
+		err := c.WriteJSON(types.HttpSuccessResponse{Data: map[string]any{key: user}})
+		key = "user"
+		return err
+	})
 
-	cur, err := coll.Watch(context.TODO(), mongo.Pipeline{})
-	if err != nil {
+	switch {
+	case err == store.ErrNotFound:
+		c.WriteJSON(types.ErrorResponse{Error: "User Not Found!"})
+	case err == store.ErrNotSupported:
+		c.WriteJSON(types.ErrorResponse{Error: "Real time user data is " + err.Error()})
+	case err != nil:
 		c.WriteJSON(types.ErrorResponse{Error: "Failed to establish change stream: " + err.Error()})
-		c.Close()
-		return
-	}
-
-	defer cur.Close(context.TODO())
-
-	for cur.Next(context.TODO()) {
-		user, err = utils.FindUserFromMongoDBUsingID(user.ID, coll)
-		if err != nil {
-			c.WriteJSON("Something Went Wrong")
-			c.Close()
-			return
-		}
-		c.WriteJSON(types.HttpSuccessResponse{Data: map[string]any{"user": user}})
 	}
+	c.Close()
 }
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/froggy-12/mooshroombase_v2/services/authentication/store"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestDeleteUser(t *testing.T) {
+	users := setup(t)
+	user := mustCreateUser(t, users, types.AuthUser{Password: "password"})
+	current := mustLogIn(t, user.ID)
+	other := mustLogIn(t, user.ID)
+	app := protected(http.MethodDelete, "/delete-user", func(c *fiber.Ctx) error {
+		return DeleteUser(c, users, validate)
+	})
+
+	body := map[string]string{"email": user.Email, "password": "wrong password"}
+	if res, _, raw := send(t, app, http.MethodDelete, "/delete-user", body, current.AccessToken); res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("DeleteUser() with a wrong password = %d %s, want 400", res.StatusCode, raw)
+	}
+
+	body["password"] = "password"
+	if res, _, raw := send(t, app, http.MethodDelete, "/delete-user", body, current.AccessToken); res.StatusCode != http.StatusAccepted {
+		t.Fatalf("DeleteUser() = %d %s, want 202", res.StatusCode, raw)
+	}
+	if _, err := users.FindUserByID(user.ID); err != store.ErrNotFound {
+		t.Errorf("FindUserByID() of the deleted user error = %v, want %v", err, store.ErrNotFound)
+	}
+
+	// every device of the deleted user is logged out
+	for _, pair := range []sessions.TokenPair{current, other} {
+		if revoked, err := sessions.IsSessionRevoked(pair.SessionID); err != nil || !revoked {
+			t.Errorf("IsSessionRevoked() = %v, %v, want the session revoked", revoked, err)
+		}
+		if _, err := sessions.Rotate(pair.RefreshToken); err == nil {
+			t.Errorf("Rotate() of a refresh token of the deleted user succeeded")
+		}
+	}
+	if res, _, raw := send(t, app, http.MethodDelete, "/delete-user", body, other.AccessToken); res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("request with a token of the deleted user = %d %s, want 401", res.StatusCode, raw)
+	}
+}
@@ -0,0 +1,120 @@
+package magiclink
+
+import (
+	"errors"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const tokenType = "magic-link"
+
+var ErrInvalidLink = errors.New("invalid or expired log in link")
+
+func expiration() time.Duration {
+	if configs.Configs.Authentication.MagicLinkTokenExpiration <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Minute * time.Duration(configs.Configs.Authentication.MagicLinkTokenExpiration)
+}
+
+// creates the signed link for the email, its jti is stored so the link works only once
+func Issue(email string) (string, error) {
+	jti := uuid.New().String()
+	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": email,
+		"jti": jti,
+		"typ": tokenType,
+		"exp": time.Now().Add(expiration()).Unix(),
+		"iat": time.Now().Unix(),
+	})
+
+	token, err := claims.SignedString([]byte(configs.Configs.HttpConfigurations.JWTSecret))
+	if err != nil {
+		return "", err
+	}
+
+	if err := sessions.StoreOneTimeToken(tokenType, jti, expiration()); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(configs.Configs.Applications.BackEndURlWithDomain, "/") + "/api/auth/magic-link/verify?token=" + url.QueryEscape(token), nil
+}
+
+func parse(token string) (string, string, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return []byte(configs.Configs.HttpConfigurations.JWTSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return "", "", ErrInvalidLink
+	}
+
+	email, _ := claims["sub"].(string)
+	jti, _ := claims["jti"].(string)
+	if claims["typ"] != tokenType || email == "" || jti == "" {
+		return "", "", ErrInvalidLink
+	}
+	return email, jti, nil
+}
+
+// checks the signature and expiry of the link without using it up, mail scanners open links before the user does
+func Check(token string) error {
+	_, _, err := parse(token)
+	return err
+}
+
+// checks the link and uses it up, returns the email it was sent to
+func Verify(token string) (string, error) {
+	email, jti, err := parse(token)
+	if err != nil {
+		return "", err
+	}
+
+	unused, err := sessions.ConsumeOneTimeToken(tokenType, jti)
+	if err != nil {
+		return "", err
+	}
+	if !unused {
+		return "", ErrInvalidLink
+	}
+
+	return email, nil
+}
+
+// opening the link only asks the user to confirm the log in, the frontend gets the token as ?token= and sends it back
+// with a POST, without a frontend a page with a button does the same
+func Confirm(c *fiber.Ctx, token string) error {
+	if redirectURL := configs.Configs.Authentication.MagicLinkRedirectURL; redirectURL != "" {
+		return c.Redirect(redirectURL+"?token="+url.QueryEscape(token), http.StatusSeeOther)
+	}
+
+	c.Type("html")
+	return c.SendString(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="robots" content="noindex"><title>Log in</title></head>
+<body>
+<form method="post">
+<input type="hidden" name="token" value="` + html.EscapeString(token) + `">
+<button type="submit">Log in</button>
+</form>
+</body>
+</html>
+`)
+}
+
+// username for accounts created by a magic link, taken from the local part of the email
+func UserNameFromEmail(email string) string {
+	if at := strings.Index(email, "@"); at > 0 {
+		return email[:at]
+	}
+	return email
+}
@@ -0,0 +1,176 @@
+package magiclink
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+func setup(t *testing.T) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	sessions.Store = sessions.NewRedisStorage(client)
+
+	previous := configs.Configs
+	configs.Configs.HttpConfigurations.JWTSecret = "secret"
+	configs.Configs.Applications.BackEndURlWithDomain = "https://auth.example.com"
+	t.Cleanup(func() { configs.Configs = previous })
+}
+
+// issues a link and returns the token in it
+func mustIssue(t *testing.T, email string) string {
+	t.Helper()
+	link, err := Issue(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Query().Get("token")
+}
+
+func sign(t *testing.T, secret string, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name string
+		// returns the token to verify
+		prepare func(t *testing.T) string
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "issued link",
+			prepare: func(t *testing.T) string { return mustIssue(t, "user@example.com") },
+			want:    "user@example.com",
+		},
+		{
+			name: "used link",
+			prepare: func(t *testing.T) string {
+				token := mustIssue(t, "user@example.com")
+				if _, err := Verify(token); err != nil {
+					t.Fatal(err)
+				}
+				return token
+			},
+			wantErr: ErrInvalidLink,
+		},
+		{
+			// mail scanners open the link before the user does
+			name: "checked link",
+			prepare: func(t *testing.T) string {
+				token := mustIssue(t, "user@example.com")
+				for range 2 {
+					if err := Check(token); err != nil {
+						t.Fatal(err)
+					}
+				}
+				return token
+			},
+			want: "user@example.com",
+		},
+		{
+			name: "expired link",
+			prepare: func(t *testing.T) string {
+				return sign(t, "secret", jwt.MapClaims{"sub": "user@example.com", "jti": "id", "typ": tokenType, "exp": time.Now().Add(-time.Minute).Unix()})
+			},
+			wantErr: ErrInvalidLink,
+		},
+		{
+			name: "signed with another secret",
+			prepare: func(t *testing.T) string {
+				return sign(t, "other", jwt.MapClaims{"sub": "user@example.com", "jti": "id", "typ": tokenType, "exp": time.Now().Add(time.Minute).Unix()})
+			},
+			wantErr: ErrInvalidLink,
+		},
+		{
+			// other tokens signed with the same secret like access tokens are no log in links
+			name: "other token type",
+			prepare: func(t *testing.T) string {
+				return sign(t, "secret", jwt.MapClaims{"sub": "user@example.com", "jti": "id", "exp": time.Now().Add(time.Minute).Unix()})
+			},
+			wantErr: ErrInvalidLink,
+		},
+		{
+			name: "link which was never issued",
+			prepare: func(t *testing.T) string {
+				return sign(t, "secret", jwt.MapClaims{"sub": "user@example.com", "jti": "id", "typ": tokenType, "exp": time.Now().Add(time.Minute).Unix()})
+			},
+			wantErr: ErrInvalidLink,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setup(t)
+			email, err := Verify(test.prepare(t))
+			if err != test.wantErr || email != test.want {
+				t.Errorf("Verify() = %q, %v, want %q, %v", email, err, test.want, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		name         string
+		redirectURL  string
+		wantStatus   int
+		wantLocation string
+		wantBody     string
+	}{
+		{
+			name:         "frontend",
+			redirectURL:  "https://app.example.com/magic-link",
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "https://app.example.com/magic-link?token=a%3Cb",
+		},
+		{
+			name:       "page with a log in button",
+			wantStatus: http.StatusOK,
+			wantBody:   `<input type="hidden" name="token" value="a&lt;b">`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setup(t)
+			configs.Configs.Authentication.MagicLinkRedirectURL = test.redirectURL
+
+			app := fiber.New()
+			app.Get("/verify", func(c *fiber.Ctx) error {
+				return Confirm(c, "a<b")
+			})
+			res, err := app.Test(httptest.NewRequest(http.MethodGet, "/verify", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(res.Body)
+			if res.StatusCode != test.wantStatus || res.Header.Get("Location") != test.wantLocation || !strings.Contains(string(body), test.wantBody) {
+				t.Errorf("Confirm() = %d %q %s, want %d %q with %q", res.StatusCode, res.Header.Get("Location"), body, test.wantStatus, test.wantLocation, test.wantBody)
+			}
+		})
+	}
+}
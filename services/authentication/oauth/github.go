@@ -0,0 +1,106 @@
+package oauth
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/oauth2"
+)
+
+const GithubProvider = "github"
+
+func githubConfig() *oauth2.Config {
+	authURL := configs.Configs.Authentication.GithubOAuthAuthURL
+	if authURL == "" {
+		authURL = "https://github.com/login/oauth/authorize"
+	}
+	tokenURL := configs.Configs.Authentication.GithubOAuthTokenURL
+	if tokenURL == "" {
+		tokenURL = "https://github.com/login/oauth/access_token"
+	}
+
+	return &oauth2.Config{
+		ClientID:     configs.Configs.Authentication.GithubOAuthAppID,
+		ClientSecret: configs.Configs.Authentication.GithubOAuthAppSecret,
+		RedirectURL:  CallbackURL(GithubProvider),
+		Scopes:       []string{"read:user", "user:email"},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   authURL,
+			TokenURL:  tokenURL,
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}
+}
+
+func githubAPIURL() string {
+	if configs.Configs.Authentication.GithubOAuthAPIURL == "" {
+		return "https://api.github.com"
+	}
+	return strings.TrimSuffix(configs.Configs.Authentication.GithubOAuthAPIURL, "/")
+}
+
+func StartGithubOAuth(c *fiber.Ctx) error {
+	return StartAuthorization(c, githubConfig(), LogInIntent)
+}
+
+func StartGithubLink(c *fiber.Ctx) error {
+	return StartAuthorization(c, githubConfig(), LinkIntent)
+}
+
+// handles the callback part of the github flow and returns the profile of the authorized user with the intent of the flow
+// only the primary verified email of the github account is trusted
+func GithubUserProfile(c *fiber.Ctx) (types.OAuthUserProfile, string, error) {
+	config := githubConfig()
+	token, intent, err := ExchangeCode(c, config)
+	if err != nil {
+		return types.OAuthUserProfile{}, "", err
+	}
+
+	var user struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := FetchJSON(config, token, githubAPIURL()+"/user", &user); err != nil {
+		return types.OAuthUserProfile{}, "", err
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := FetchJSON(config, token, githubAPIURL()+"/user/emails", &emails); err != nil {
+		return types.OAuthUserProfile{}, "", err
+	}
+
+	profile := types.OAuthUserProfile{
+		Provider:       GithubProvider,
+		ProviderUserID: strconv.FormatInt(user.ID, 10),
+		UserName:       user.Login,
+		ProfilePicture: user.AvatarURL,
+	}
+	profile.FirstName, profile.LastName, _ = strings.Cut(user.Name, " ")
+
+	for _, email := range emails {
+		if email.Primary && email.Verified {
+			profile.Email = email.Email
+			profile.EmailVerified = true
+			break
+		}
+	}
+
+	if user.ID == 0 {
+		return types.OAuthUserProfile{}, "", errors.New("github did not return the user id")
+	}
+	if profile.Email == "" {
+		return types.OAuthUserProfile{}, "", errors.New("github account doesnt have a verified primary email")
+	}
+
+	return profile, intent, nil
+}
@@ -0,0 +1,86 @@
+package oauth
+
+import (
+	"errors"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/oauth2"
+)
+
+const GoogleProvider = "google"
+
+func googleConfig() *oauth2.Config {
+	authURL := configs.Configs.Authentication.GoogleOAuthAuthURL
+	if authURL == "" {
+		authURL = "https://accounts.google.com/o/oauth2/v2/auth"
+	}
+	tokenURL := configs.Configs.Authentication.GoogleOAuthTokenURL
+	if tokenURL == "" {
+		tokenURL = "https://oauth2.googleapis.com/token"
+	}
+
+	return &oauth2.Config{
+		ClientID:     configs.Configs.Authentication.GoogleOAuthAppID,
+		ClientSecret: configs.Configs.Authentication.GoogleOAuthAppSecret,
+		RedirectURL:  CallbackURL(GoogleProvider),
+		Scopes:       []string{"openid", "email", "profile"},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   authURL,
+			TokenURL:  tokenURL,
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}
+}
+
+func googleUserInfoURL() string {
+	if configs.Configs.Authentication.GoogleOAuthUserInfoURL == "" {
+		return "https://openidconnect.googleapis.com/v1/userinfo"
+	}
+	return configs.Configs.Authentication.GoogleOAuthUserInfoURL
+}
+
+func StartGoogleOAuth(c *fiber.Ctx) error {
+	return StartAuthorization(c, googleConfig(), LogInIntent)
+}
+
+func StartGoogleLink(c *fiber.Ctx) error {
+	return StartAuthorization(c, googleConfig(), LinkIntent)
+}
+
+// handles the callback part of the google flow and returns the profile of the authorized user with the intent of the flow
+func GoogleUserProfile(c *fiber.Ctx) (types.OAuthUserProfile, string, error) {
+	config := googleConfig()
+	token, intent, err := ExchangeCode(c, config)
+	if err != nil {
+		return types.OAuthUserProfile{}, "", err
+	}
+
+	var info struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		GivenName     string `json:"given_name"`
+		FamilyName    string `json:"family_name"`
+		Picture       string `json:"picture"`
+	}
+
+	if err := FetchJSON(config, token, googleUserInfoURL(), &info); err != nil {
+		return types.OAuthUserProfile{}, "", err
+	}
+
+	if info.Sub == "" || info.Email == "" {
+		return types.OAuthUserProfile{}, "", errors.New("google did not return the user id or email")
+	}
+
+	return types.OAuthUserProfile{
+		Provider:       GoogleProvider,
+		ProviderUserID: info.Sub,
+		FirstName:      info.GivenName,
+		LastName:       info.FamilyName,
+		Email:          info.Email,
+		EmailVerified:  info.EmailVerified,
+		ProfilePicture: info.Picture,
+	}, intent, nil
+}
@@ -0,0 +1,167 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/froggy-12/mooshroombase_v2/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+)
+
+const (
+	stateCookieName = "oauthState"
+	stateTTL        = 10 * time.Minute
+)
+
+// callback url registered on the provider side for example http://localhost:6644/api/auth/oauth/google/callback
+func CallbackURL(provider string) string {
+	return strings.TrimSuffix(configs.Configs.Applications.BackEndURlWithDomain, "/") + "/api/auth/oauth/" + provider + "/callback"
+}
+
+const (
+	LogInIntent = "login" // the callback should log the user in or create a new account
+	LinkIntent  = "link"  // the callback should link the provider account to the logged in user
+)
+
+// redirects the user to the provider consent screen with a random state stored in a short lived cookie
+// link flows have to run behind the jwt middleware, the session of the request is stored with the state
+func StartAuthorization(c *fiber.Ctx, config *oauth2.Config, intent string, opts ...oauth2.AuthCodeOption) error {
+	state := uuid.New().String()
+	if intent == LinkIntent {
+		sessionID, _ := c.Locals("sessionId").(string)
+		if sessionID == "" {
+			return c.Status(http.StatusUnauthorized).JSON(types.ErrorResponse{Error: "Please log in before linking an account"})
+		}
+		if err := sessions.StoreOAuthLink(state, sessionID, stateTTL); err != nil {
+			return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to start linking: " + err.Error()})
+		}
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     stateCookieName,
+		Value:    intent + ":" + state,
+		Path:     "/api/auth/oauth",
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+		MaxAge:   int(stateTTL.Seconds()),
+	})
+	return c.Redirect(config.AuthCodeURL(state, opts...), http.StatusTemporaryRedirect)
+}
+
+// checks the state and exchanges the authorization code from the callback request for a token, it also returns the intent the flow was started with
+func ExchangeCode(c *fiber.Ctx, config *oauth2.Config, opts ...oauth2.AuthCodeOption) (*oauth2.Token, string, error) {
+	if errParam := c.Query("error"); errParam != "" {
+		return nil, "", errors.New("provider returned an error: " + errParam)
+	}
+
+	intent, state, found := strings.Cut(c.Cookies(stateCookieName), ":")
+	if !found || state == "" || state != c.Query("state") {
+		return nil, "", errors.New("invalid oauth state")
+	}
+	c.ClearCookie(stateCookieName)
+
+	code := c.Query("code")
+	if code == "" {
+		return nil, "", errors.New("authorization code is missing")
+	}
+
+	token, err := config.Exchange(context.Background(), code, opts...)
+	return token, intent, err
+}
+
+// fetches a json document from the provider using the access token
+func FetchJSON(config *oauth2.Config, token *oauth2.Token, url string, v any) error {
+	client := config.Client(context.Background(), token)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("provider responded with status %v", res.StatusCode)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// builds a username for a new oauth user from the profile, it might be taken already so the caller should check it
+func UserNameFromProfile(profile types.OAuthUserProfile) string {
+	if profile.UserName != "" {
+		return profile.UserName
+	}
+	if at := strings.Index(profile.Email, "@"); at > 0 {
+		return profile.Email[:at]
+	}
+	return profile.Provider + "-" + profile.ProviderUserID
+}
+
+// makes a username unique by adding a short random suffix
+func RandomizeUserName(username string) string {
+	return username + "-" + uuid.New().String()[:6]
+}
+
+// id of the user who started the link flow, the callback route is not protected by the jwt middleware
+// so the session stored with the state is checked again in case it was revoked in the meantime
+func LoggedInUserID(c *fiber.Ctx) (string, error) {
+	sessionID, err := sessions.TakeOAuthLink(c.Query("state"))
+	if err != nil {
+		return "", errors.New("please log in before linking an account")
+	}
+	userID, err := sessions.SessionUserID(sessionID)
+	if err != nil || userID == "" {
+		return "", errors.New("please log in before linking an account")
+	}
+	return userID, nil
+}
+
+// sends the user back to the frontend if configured otherwise responds with json
+func Respond(c *fiber.Ctx, message string, data map[string]any) error {
+	if configs.Configs.Authentication.OAuthRedirectURL != "" {
+		return c.Redirect(configs.Configs.Authentication.OAuthRedirectURL, http.StatusSeeOther)
+	}
+
+	return c.Status(http.StatusAccepted).JSON(types.HttpSuccessResponse{Message: message, Data: data})
+}
+
+// users with two factor enabled still have to send their code, the frontend gets the challenge token as ?mfaToken=
+func TwoFactorChallenge(c *fiber.Ctx, userID string) error {
+	if configs.Configs.Authentication.OAuthRedirectURL == "" {
+		return utils.TwoFactorChallenge(c, userID)
+	}
+
+	token, err := sessions.CreateMFAChallenge(userID)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to create two factor challenge: " + err.Error()})
+	}
+
+	return c.Redirect(configs.Configs.Authentication.OAuthRedirectURL+"?mfaToken="+url.QueryEscape(token), http.StatusSeeOther)
+}
+
+// creates a session with its token cookies and sends the user back to the frontend if configured otherwise responds with json
+// the callback is opened by the redirect of the provider so clients cant ask for the tokens in the body here
+func FinishLogIn(c *fiber.Ctx, userID string) error {
+	if _, err := sessions.LogIn(c, userID); err != nil {
+		return utils.SessionError(c, err)
+	}
+
+	return Respond(c, "User has been logged in successfully", map[string]any{"userID": userID})
+}
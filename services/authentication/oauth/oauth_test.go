@@ -0,0 +1,253 @@
+package oauth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/oauth2"
+)
+
+var testConfig = &oauth2.Config{
+	ClientID: "client",
+	Endpoint: oauth2.Endpoint{AuthURL: "https://provider.example.com/authorize", TokenURL: "https://provider.example.com/token"},
+}
+
+func setup(t *testing.T) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	sessions.Store = sessions.NewRedisStorage(client)
+
+	previous := configs.Configs.HttpConfigurations
+	configs.Configs.HttpConfigurations = configs.HttpConfigurations{JWTSecret: "secret", JWTTokenExpirationTime: 7, AccessTokenExpirationTime: 15}
+	t.Cleanup(func() { configs.Configs.HttpConfigurations = previous })
+}
+
+// starts a flow as the session and returns the state sent to the provider and the state cookie
+func start(t *testing.T, intent, sessionID string) (*http.Response, string, *http.Cookie) {
+	t.Helper()
+	app := fiber.New()
+	app.Get("/start", func(c *fiber.Ctx) error {
+		if sessionID != "" {
+			c.Locals("sessionId", sessionID)
+		}
+		return StartAuthorization(c, testConfig, intent)
+	})
+
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/start", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusTemporaryRedirect {
+		return res, "", nil
+	}
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cookie := range res.Cookies() {
+		if cookie.Name == stateCookieName {
+			return res, location.Query().Get("state"), cookie
+		}
+	}
+	t.Fatal("the state cookie wasnt set")
+	return nil, "", nil
+}
+
+// runs LoggedInUserID in a callback request with the state
+func loggedInUserID(t *testing.T, state string) (string, error) {
+	t.Helper()
+	var userID string
+	var err error
+	app := fiber.New()
+	app.Get("/callback", func(c *fiber.Ctx) error {
+		userID, err = LoggedInUserID(c)
+		return nil
+	})
+	if _, testErr := app.Test(httptest.NewRequest(http.MethodGet, "/callback?state="+url.QueryEscape(state), nil)); testErr != nil {
+		t.Fatal(testErr)
+	}
+	return userID, err
+}
+
+func mustCreateSession(t *testing.T, userID string) sessions.TokenPair {
+	t.Helper()
+	pair, err := sessions.CreateSession(userID, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pair
+}
+
+func TestLinkNeedsASession(t *testing.T) {
+	setup(t)
+	res, _, _ := start(t, LinkIntent, "")
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("starting a link flow without a session = %d, want %d", res.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestLoggedInUserID(t *testing.T) {
+	tests := []struct {
+		name string
+		// returns the state sent back by the provider
+		prepare func(t *testing.T) string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "session which started the flow",
+			prepare: func(t *testing.T) string {
+				_, state, _ := start(t, LinkIntent, mustCreateSession(t, "user").SessionID)
+				return state
+			},
+			want: "user",
+		},
+		{
+			name: "state which was used already",
+			prepare: func(t *testing.T) string {
+				_, state, _ := start(t, LinkIntent, mustCreateSession(t, "user").SessionID)
+				if _, err := loggedInUserID(t, state); err != nil {
+					t.Fatal(err)
+				}
+				return state
+			},
+			wantErr: true,
+		},
+		{
+			// the session was revoked while the user was at the provider
+			name: "revoked session",
+			prepare: func(t *testing.T) string {
+				pair := mustCreateSession(t, "user")
+				_, state, _ := start(t, LinkIntent, pair.SessionID)
+				if err := sessions.RevokeSession(pair.SessionID); err != nil {
+					t.Fatal(err)
+				}
+				return state
+			},
+			wantErr: true,
+		},
+		{
+			name: "state of a log in flow",
+			prepare: func(t *testing.T) string {
+				_, state, _ := start(t, LogInIntent, "")
+				return state
+			},
+			wantErr: true,
+		},
+		{
+			name:    "made up state",
+			prepare: func(t *testing.T) string { return "made-up" },
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setup(t)
+			userID, err := loggedInUserID(t, test.prepare(t))
+			if (err != nil) != test.wantErr || userID != test.want {
+				t.Errorf("LoggedInUserID() = %q, %v, want %q", userID, err, test.want)
+			}
+		})
+	}
+}
+
+func TestExchangeCodeChecksTheState(t *testing.T) {
+	setup(t)
+	_, state, cookie := start(t, LogInIntent, "")
+
+	tests := []struct {
+		name    string
+		query   string
+		cookie  *http.Cookie
+		wantErr string
+	}{
+		{"provider error", "?error=access_denied&state=" + state, cookie, "provider returned an error"},
+		{"without the state cookie", "?code=code&state=" + state, nil, "invalid oauth state"},
+		{"other state", "?code=code&state=other", cookie, "invalid oauth state"},
+		{"without a state", "?code=code", cookie, "invalid oauth state"},
+		{"without a code", "?state=" + state, cookie, "authorization code is missing"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/callback", func(c *fiber.Ctx) error {
+				_, _, err := ExchangeCode(c, testConfig)
+				if err == nil {
+					return c.SendString("")
+				}
+				return c.SendString(err.Error())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/callback"+test.query, nil)
+			if test.cookie != nil {
+				req.AddCookie(test.cookie)
+			}
+			res, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(res.Body)
+			if !strings.Contains(string(body), test.wantErr) {
+				t.Errorf("ExchangeCode() error = %q, want %q", body, test.wantErr)
+			}
+		})
+	}
+}
+
+// the provider redirects to the callback so the tokens always end up in cookies and never in the body
+func TestFinishLogIn(t *testing.T) {
+	tests := []struct {
+		name        string
+		redirectURL string
+		wantStatus  int
+	}{
+		{"json response", "", http.StatusAccepted},
+		{"redirect to the frontend", "https://app.example.com/logged-in", http.StatusSeeOther},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setup(t)
+			previous := configs.Configs.Authentication.OAuthRedirectURL
+			configs.Configs.Authentication.OAuthRedirectURL = test.redirectURL
+			t.Cleanup(func() { configs.Configs.Authentication.OAuthRedirectURL = previous })
+
+			app := fiber.New()
+			app.Get("/callback", func(c *fiber.Ctx) error {
+				return FinishLogIn(c, "user")
+			})
+			res, err := app.Test(httptest.NewRequest(http.MethodGet, "/callback?code=code&state=state", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(res.Body)
+			if res.StatusCode != test.wantStatus || strings.Contains(string(body), "accessToken") {
+				t.Fatalf("FinishLogIn() = %d %s, want %d without tokens", res.StatusCode, body, test.wantStatus)
+			}
+			if location := res.Header.Get("Location"); test.redirectURL != "" && location != test.redirectURL {
+				t.Errorf("FinishLogIn() redirected to %q, want %q", location, test.redirectURL)
+			}
+
+			cookies := map[string]string{}
+			for _, cookie := range res.Cookies() {
+				cookies[cookie.Name] = cookie.Value
+			}
+			if _, err := sessions.Rotate(cookies[sessions.RefreshTokenCookieName]); err != nil || cookies[sessions.AccessTokenCookieName] == "" {
+				t.Errorf("FinishLogIn() set the cookies %v, the refresh token cant be used: %v", cookies, err)
+			}
+		})
+	}
+}
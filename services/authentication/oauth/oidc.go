@@ -0,0 +1,201 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/singleflight"
+)
+
+const pkceCookieName = "oauthPKCE"
+
+// discovered providers are cached per issuer so the discovery document is only loaded once, the lock only
+// guards the map and concurrent discoveries of the same issuer share one request
+var (
+	oidcProviders   = map[string]*oidc.Provider{}
+	oidcProvidersMu sync.Mutex
+	oidcDiscoveries singleflight.Group
+)
+
+// provider name stored in the linked accounts for example oidc:keycloak
+func OIDCProviderID(name string) string {
+	return "oidc:" + name
+}
+
+func cachedOIDCProvider(issuer string) (*oidc.Provider, bool) {
+	oidcProvidersMu.Lock()
+	defer oidcProvidersMu.Unlock()
+	provider, ok := oidcProviders[issuer]
+	return provider, ok
+}
+
+// the discovery runs without the lock so a slow provider doesnt hold up log ins with the others
+func discoverOIDCProvider(providerConfig configs.OIDCProvider) (*oidc.Provider, error) {
+	issuer := providerConfig.IssuerURL
+	if provider, ok := cachedOIDCProvider(issuer); ok {
+		return provider, nil
+	}
+
+	provider, err, _ := oidcDiscoveries.Do(issuer, func() (any, error) {
+		// a discovery which finished after the cache was checked already stored the provider
+		if provider, ok := cachedOIDCProvider(issuer); ok {
+			return provider, nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		provider, err := oidc.NewProvider(ctx, issuer)
+		if err != nil {
+			return nil, err
+		}
+
+		oidcProvidersMu.Lock()
+		oidcProviders[issuer] = provider
+		oidcProvidersMu.Unlock()
+		return provider, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return provider.(*oidc.Provider), nil
+}
+
+func oidcConfig(providerConfig configs.OIDCProvider) (*oauth2.Config, *oidc.Provider, error) {
+	provider, err := discoverOIDCProvider(providerConfig)
+	if err != nil {
+		return nil, nil, errors.New("failed to discover provider " + providerConfig.Name + ": " + err.Error())
+	}
+
+	scopes := providerConfig.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+
+	return &oauth2.Config{
+		ClientID:     providerConfig.ClientID,
+		ClientSecret: providerConfig.ClientSecret,
+		RedirectURL:  CallbackURL("oidc/" + providerConfig.Name),
+		Scopes:       scopes,
+		Endpoint:     provider.Endpoint(),
+	}, provider, nil
+}
+
+// starts the authorization code flow with pkce and a nonce, both are kept in a short lived cookie until the callback
+func StartOIDC(c *fiber.Ctx, providerConfig configs.OIDCProvider, intent string) error {
+	config, _, err := oidcConfig(providerConfig)
+	if err != nil {
+		return c.Status(http.StatusBadGateway).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+
+	verifier := oauth2.GenerateVerifier()
+	nonce := uuid.New().String()
+	c.Cookie(&fiber.Cookie{
+		Name:     pkceCookieName,
+		Value:    verifier + ":" + nonce,
+		Path:     "/api/auth/oauth",
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+		MaxAge:   int((10 * time.Minute).Seconds()),
+	})
+
+	return StartAuthorization(c, config, intent, oauth2.S256ChallengeOption(verifier), oidc.Nonce(nonce))
+}
+
+// handles the callback part of the oidc flow, verifies the id token against the provider jwks and maps its claims onto the user fields
+func OIDCUserProfile(c *fiber.Ctx, providerConfig configs.OIDCProvider) (types.OAuthUserProfile, string, error) {
+	config, provider, err := oidcConfig(providerConfig)
+	if err != nil {
+		return types.OAuthUserProfile{}, "", err
+	}
+
+	verifier, nonce, found := strings.Cut(c.Cookies(pkceCookieName), ":")
+	if !found || verifier == "" {
+		return types.OAuthUserProfile{}, "", errors.New("pkce verifier is missing")
+	}
+	c.ClearCookie(pkceCookieName)
+
+	token, intent, err := ExchangeCode(c, config, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return types.OAuthUserProfile{}, "", err
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return types.OAuthUserProfile{}, "", errors.New("provider did not return an id token")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: providerConfig.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return types.OAuthUserProfile{}, "", errors.New("invalid id token: " + err.Error())
+	}
+	if idToken.Nonce != nonce {
+		return types.OAuthUserProfile{}, "", errors.New("invalid id token nonce")
+	}
+
+	claims := map[string]any{}
+	if err := idToken.Claims(&claims); err != nil {
+		return types.OAuthUserProfile{}, "", err
+	}
+
+	// some providers only put the profile claims into the userinfo response
+	if provider.UserInfoEndpoint() != "" {
+		userInfo, err := provider.UserInfo(ctx, config.TokenSource(ctx, token))
+		if err == nil && userInfo.Subject == idToken.Subject {
+			userInfoClaims := map[string]any{}
+			if err := userInfo.Claims(&userInfoClaims); err == nil {
+				for key, value := range userInfoClaims {
+					if _, exists := claims[key]; !exists {
+						claims[key] = value
+					}
+				}
+			}
+		}
+	}
+
+	mapping := providerConfig.ClaimMapping
+	profile := types.OAuthUserProfile{
+		Provider:       OIDCProviderID(providerConfig.Name),
+		ProviderUserID: idToken.Subject,
+		UserName:       stringClaim(claims, mapping.UserName, "preferred_username"),
+		FirstName:      stringClaim(claims, mapping.FirstName, "given_name"),
+		LastName:       stringClaim(claims, mapping.LastName, "family_name"),
+		Email:          stringClaim(claims, mapping.Email, "email"),
+		ProfilePicture: stringClaim(claims, mapping.ProfilePicture, "picture"),
+	}
+
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		profile.EmailVerified = verified
+	case string:
+		profile.EmailVerified = verified == "true"
+	}
+
+	if profile.Email == "" {
+		return types.OAuthUserProfile{}, "", errors.New("provider did not return an email claim")
+	}
+
+	return profile, intent, nil
+}
+
+func stringClaim(claims map[string]any, name, defaultName string) string {
+	if name == "" {
+		name = defaultName
+	}
+	value, _ := claims[name].(string)
+	return value
+}
@@ -0,0 +1,226 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// an oidc provider which signs its id tokens with a fresh key, idToken returns the claims of the next token
+type testProvider struct {
+	server      *httptest.Server
+	key         *rsa.PrivateKey
+	discoveries atomic.Int32
+	// the discovery waits for it when it is set
+	release chan struct{}
+	idToken func(nonce string) jwt.MapClaims
+	// the nonce of the last authorization request
+	nonce string
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := &testProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		provider.discoveries.Add(1)
+		if provider.release != nil {
+			<-provider.release
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                provider.server.URL,
+			"authorization_endpoint":                provider.server.URL + "/authorize",
+			"token_endpoint":                        provider.server.URL + "/token",
+			"jwks_uri":                              provider.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, provider.idToken(provider.nonce))
+		token.Header["kid"] = "test"
+		signed, err := token.SignedString(key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"access_token": "access", "token_type": "Bearer", "expires_in": 60, "id_token": signed})
+	})
+
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+	t.Cleanup(func() {
+		oidcProvidersMu.Lock()
+		delete(oidcProviders, provider.server.URL)
+		oidcProvidersMu.Unlock()
+	})
+	return provider
+}
+
+func (p *testProvider) config() configs.OIDCProvider {
+	return configs.OIDCProvider{Name: "test", IssuerURL: p.server.URL, ClientID: "client"}
+}
+
+func TestDiscoverOIDCProvider(t *testing.T) {
+	slow := newTestProvider(t)
+	other := newTestProvider(t)
+	if _, err := discoverOIDCProvider(other.config()); err != nil {
+		t.Fatal(err)
+	}
+
+	slow.release = make(chan struct{})
+	var wg sync.WaitGroup
+	discovered := make(chan any, 5)
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			provider, err := discoverOIDCProvider(slow.config())
+			if err != nil {
+				t.Error(err)
+			}
+			discovered <- provider
+		}()
+	}
+	for deadline := time.Now().Add(5 * time.Second); slow.discoveries.Load() == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			close(slow.release)
+			t.Fatal("the discovery of the provider never started")
+		}
+	}
+
+	// the cached provider is returned while the other one is still being discovered
+	done := make(chan error)
+	go func() {
+		_, err := discoverOIDCProvider(other.config())
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("discoverOIDCProvider() of a cached provider waited for the discovery of another one")
+	}
+
+	close(slow.release)
+	wg.Wait()
+	close(discovered)
+	first := <-discovered
+	for provider := range discovered {
+		if provider != first {
+			t.Errorf("discoverOIDCProvider() returned different providers for the same issuer")
+		}
+	}
+	if count := slow.discoveries.Load(); count != 1 {
+		t.Errorf("the discovery document was loaded %d times, want once", count)
+	}
+	if count := other.discoveries.Load(); count != 1 {
+		t.Errorf("the discovery document of the cached provider was loaded %d times, want once", count)
+	}
+}
+
+// starts the flow and sends the callback with the cookies it set
+func oidcLogIn(t *testing.T, provider *testProvider) error {
+	t.Helper()
+	app := fiber.New()
+	app.Get("/start", func(c *fiber.Ctx) error {
+		return StartOIDC(c, provider.config(), LogInIntent)
+	})
+	var profileErr error
+	app.Get("/callback", func(c *fiber.Ctx) error {
+		_, _, profileErr = OIDCUserProfile(c, provider.config())
+		return nil
+	})
+
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/start", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.nonce = location.Query().Get("nonce")
+
+	req := httptest.NewRequest(http.MethodGet, "/callback?code=code&state="+url.QueryEscape(location.Query().Get("state")), nil)
+	for _, cookie := range res.Cookies() {
+		req.AddCookie(cookie)
+	}
+	if _, err := app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+	return profileErr
+}
+
+func TestOIDCUserProfileChecksTheNonce(t *testing.T) {
+	tests := []struct {
+		name string
+		// the nonce put into the id token for the nonce of the authorization request
+		nonce   func(sent string) string
+		wantErr string
+	}{
+		{"nonce of the authorization request", func(sent string) string { return sent }, ""},
+		{"nonce of another flow", func(sent string) string { return "other" }, "invalid id token nonce"},
+		{"without a nonce", func(sent string) string { return "" }, "invalid id token nonce"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			provider := newTestProvider(t)
+			provider.idToken = func(sent string) jwt.MapClaims {
+				claims := jwt.MapClaims{
+					"iss":            provider.server.URL,
+					"aud":            "client",
+					"sub":            "42",
+					"iat":            time.Now().Unix(),
+					"exp":            time.Now().Add(time.Minute).Unix(),
+					"email":          "frog@example.com",
+					"email_verified": true,
+				}
+				if nonce := test.nonce(sent); nonce != "" {
+					claims["nonce"] = nonce
+				}
+				return claims
+			}
+
+			err := oidcLogIn(t, provider)
+			if test.wantErr == "" && err != nil {
+				t.Fatalf("OIDCUserProfile() error = %v", err)
+			}
+			if test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+				t.Errorf("OIDCUserProfile() error = %v, want %q", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package passkeys
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/gofiber/fiber/v2"
+)
+
+const ceremonyCookieName = "webauthnCeremony"
+
+var (
+	webAuthn     *webauthn.WebAuthn
+	webAuthnErr  error
+	webAuthnOnce sync.Once
+)
+
+// the relying party id is the host of the backend, the frontends allowed by cors can run the ceremonies too
+func instance() (*webauthn.WebAuthn, error) {
+	webAuthnOnce.Do(func() {
+		backend, err := url.Parse(configs.Configs.Applications.BackEndURlWithDomain)
+		if err != nil || backend.Hostname() == "" {
+			webAuthnErr = errors.New("BackEndURlWithDomain is not a valid url")
+			return
+		}
+
+		origins := []string{backend.Scheme + "://" + backend.Host}
+		for _, origin := range configs.Configs.Applications.AllowedCorsOrigins {
+			if origin != "*" {
+				origins = append(origins, origin)
+			}
+		}
+
+		displayName := configs.Configs.Authentication.PasskeyDisplayName
+		if displayName == "" {
+			displayName = "Mooshroombase"
+		}
+
+		webAuthn, webAuthnErr = webauthn.New(&webauthn.Config{
+			RPID:          backend.Hostname(),
+			RPDisplayName: displayName,
+			RPOrigins:     origins,
+		})
+	})
+	return webAuthn, webAuthnErr
+}
+
+// user as the webauthn library sees it, the user handle is the user id
+type User struct {
+	ID          string
+	Name        string
+	DisplayName string
+	Credentials []webauthn.Credential
+}
+
+func (u User) WebAuthnID() []byte                         { return []byte(u.ID) }
+func (u User) WebAuthnName() string                       { return u.Name }
+func (u User) WebAuthnDisplayName() string                { return u.DisplayName }
+func (u User) WebAuthnCredentials() []webauthn.Credential { return u.Credentials }
+
+func NewUser(id, email, username string, stored []types.Passkey) User {
+	user := User{ID: id, Name: email, DisplayName: username}
+	for _, passkey := range stored {
+		user.Credentials = append(user.Credentials, ToCredential(passkey))
+	}
+	return user
+}
+
+func ToCredential(passkey types.Passkey) webauthn.Credential {
+	id, _ := base64.RawURLEncoding.DecodeString(passkey.ID)
+	transports := make([]protocol.AuthenticatorTransport, 0, len(passkey.Transports))
+	for _, transport := range passkey.Transports {
+		transports = append(transports, protocol.AuthenticatorTransport(transport))
+	}
+
+	return webauthn.Credential{
+		ID:              id,
+		PublicKey:       passkey.PublicKey,
+		AttestationType: passkey.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: passkey.BackupEligible,
+			BackupState:    passkey.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    passkey.AAGUID,
+			SignCount: passkey.SignCount,
+		},
+	}
+}
+
+func FromCredential(userID, name string, credential *webauthn.Credential) types.Passkey {
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	return types.Passkey{
+		ID:              CredentialID(credential),
+		UserID:          userID,
+		Name:            name,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		SignCount:       credential.Authenticator.SignCount,
+		AAGUID:          credential.Authenticator.AAGUID,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		CreatedAt:       time.Now(),
+		LastUsedAt:      time.Now(),
+	}
+}
+
+func CredentialID(credential *webauthn.Credential) string {
+	return base64.RawURLEncoding.EncodeToString(credential.ID)
+}
+
+func storeCeremony(c *fiber.Ctx, session *webauthn.SessionData) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	id, err := sessions.StoreCeremony(data)
+	if err != nil {
+		return err
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     ceremonyCookieName,
+		Value:    id,
+		Path:     "/api",
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: fiber.CookieSameSiteStrictMode,
+		MaxAge:   int((5 * time.Minute).Seconds()),
+	})
+	return nil
+}
+
+func takeCeremony(c *fiber.Ctx) (webauthn.SessionData, error) {
+	var session webauthn.SessionData
+	id := c.Cookies(ceremonyCookieName)
+	if id == "" {
+		return session, errors.New("passkey ceremony has not been started")
+	}
+	c.ClearCookie(ceremonyCookieName)
+
+	data, err := sessions.TakeCeremony(id)
+	if err != nil {
+		return session, err
+	}
+	err = json.Unmarshal(data, &session)
+	return session, err
+}
+
+// sends the options for navigator.credentials.create, existing passkeys are excluded so they cant be registered twice
+func BeginRegistration(c *fiber.Ctx, user User) error {
+	w, err := instance()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.Credentials))
+	for _, credential := range user.Credentials {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	creation, session, err := w.BeginRegistration(user,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithExclusions(exclusions),
+	)
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to start passkey registration: " + err.Error()})
+	}
+
+	if err := storeCeremony(c, session); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to start passkey registration: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Data: map[string]any{"options": creation}})
+}
+
+// verifies the attestation sent as the request body
+func FinishRegistration(c *fiber.Ctx, user User) (*webauthn.Credential, error) {
+	w, err := instance()
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := takeCeremony(c)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(c.Body()))
+	if err != nil {
+		return nil, errors.New("invalid passkey response: " + err.Error())
+	}
+
+	return w.CreateCredential(user, session, parsed)
+}
+
+// sends the options for navigator.credentials.get, the passkey itself tells which user is logging in
+func BeginLogIn(c *fiber.Ctx) error {
+	w, err := instance()
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: err.Error()})
+	}
+
+	assertion, session, err := w.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to start passkey log in: " + err.Error()})
+	}
+
+	if err := storeCeremony(c, session); err != nil {
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to start passkey log in: " + err.Error()})
+	}
+
+	return c.Status(http.StatusOK).JSON(types.HttpSuccessResponse{Data: map[string]any{"options": assertion}})
+}
+
+// verifies the assertion sent as the request body, loadUser finds the user of the user handle with their passkeys
+func FinishLogIn(c *fiber.Ctx, loadUser func(userID string) (User, error)) (User, *webauthn.Credential, error) {
+	w, err := instance()
+	if err != nil {
+		return User{}, nil, err
+	}
+
+	session, err := takeCeremony(c)
+	if err != nil {
+		return User{}, nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(c.Body()))
+	if err != nil {
+		return User{}, nil, errors.New("invalid passkey response: " + err.Error())
+	}
+
+	webAuthnUser, credential, err := w.ValidatePasskeyLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+		return loadUser(string(userHandle))
+	}, session, parsed)
+	if err != nil {
+		return User{}, nil, err
+	}
+
+	// a sign counter going backwards means the key has been cloned
+	if credential.Authenticator.CloneWarning {
+		return User{}, nil, errors.New("this passkey might have been cloned and can not be used")
+	}
+
+	return webAuthnUser.(User), credential, nil
+}
@@ -0,0 +1,340 @@
+package passkeys
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const origin = "https://auth.example.com"
+
+// a software authenticator which answers the ceremonies like a browser would
+type authenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+	origin       string
+}
+
+func newAuthenticator(t *testing.T) *authenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialID := make([]byte, 16)
+	rand.Read(credentialID)
+	return &authenticator{key: key, credentialID: credentialID, origin: origin}
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (a *authenticator) clientData(t *testing.T, ceremony, challenge string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{"type": ceremony, "challenge": challenge, "origin": a.origin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func (a *authenticator) authenticatorData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte("auth.example.com"))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+func (a *authenticator) register(t *testing.T, challenge string) []byte {
+	t.Helper()
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{KeyType: int64(webauthncose.EllipticKey), Algorithm: int64(webauthncose.AlgES256)},
+		Curve:         1,
+		XCoord:        a.key.X.FillBytes(make([]byte, 32)),
+		YCoord:        a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attested := make([]byte, 16)
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(append(attested, a.credentialID...), publicKey...)
+
+	attestation, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authenticatorData(0x45, attested),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"id":    encode(a.credentialID),
+		"rawId": encode(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    encode(a.clientData(t, "webauthn.create", challenge)),
+			"attestationObject": encode(attestation),
+		},
+	})
+	return body
+}
+
+func (a *authenticator) logIn(t *testing.T, challenge, userID string) []byte {
+	t.Helper()
+	a.signCount++
+	authData := a.authenticatorData(0x05, nil)
+	clientData := a.clientData(t, "webauthn.get", challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"id":    encode(a.credentialID),
+		"rawId": encode(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    encode(clientData),
+			"authenticatorData": encode(authData),
+			"signature":         encode(signature),
+			"userHandle":        encode([]byte(userID)),
+		},
+	})
+	return body
+}
+
+// runs the ceremonies against the users map, registered passkeys are added to it
+func setup(t *testing.T) (*fiber.App, map[string]*User) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	sessions.Store = sessions.NewRedisStorage(client)
+	configs.Configs.Applications.BackEndURlWithDomain = origin
+
+	users := map[string]*User{"user": {ID: "user", Name: "user@example.com", DisplayName: "user"}}
+	app := fiber.New()
+	app.Post("/register/begin", func(c *fiber.Ctx) error {
+		return BeginRegistration(c, *users["user"])
+	})
+	app.Post("/register/finish", func(c *fiber.Ctx) error {
+		credential, err := FinishRegistration(c, *users["user"])
+		if err != nil {
+			return c.Status(http.StatusBadRequest).SendString(err.Error())
+		}
+		users["user"].Credentials = append(users["user"].Credentials, *credential)
+		return c.SendStatus(http.StatusOK)
+	})
+	app.Post("/login/begin", BeginLogIn)
+	app.Post("/login/finish", func(c *fiber.Ctx) error {
+		user, credential, err := FinishLogIn(c, func(userID string) (User, error) {
+			if user, ok := users[userID]; ok {
+				return *user, nil
+			}
+			return User{}, errors.New("unknown user")
+		})
+		if err != nil {
+			return c.Status(http.StatusBadRequest).SendString(err.Error())
+		}
+		// the stored sign count is updated like the handlers do
+		users[user.ID].Credentials[0].Authenticator.SignCount = credential.Authenticator.SignCount
+		return c.SendString(user.ID)
+	})
+	return app, users
+}
+
+// starts a ceremony and returns its challenge and cookie
+func begin(t *testing.T, app *fiber.App, path string) (string, *http.Cookie) {
+	t.Helper()
+	res, err := app.Test(httptest.NewRequest(http.MethodPost, path, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		Data struct {
+			Options struct {
+				PublicKey struct {
+					Challenge string `json:"challenge"`
+				} `json:"publicKey"`
+			} `json:"options"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	for _, cookie := range res.Cookies() {
+		if cookie.Name == ceremonyCookieName {
+			return body.Data.Options.PublicKey.Challenge, cookie
+		}
+	}
+	t.Fatalf("%s didnt set the ceremony cookie", path)
+	return "", nil
+}
+
+func finish(t *testing.T, app *fiber.App, path string, cookie *http.Cookie, body []byte) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	res, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(res.Body)
+	return res.StatusCode, string(data)
+}
+
+func register(t *testing.T, app *fiber.App, device *authenticator) {
+	t.Helper()
+	challenge, cookie := begin(t, app, "/register/begin")
+	if status, body := finish(t, app, "/register/finish", cookie, device.register(t, challenge)); status != http.StatusOK {
+		t.Fatalf("registration failed with %d: %s", status, body)
+	}
+}
+
+func TestRegisterAndLogIn(t *testing.T) {
+	app, users := setup(t)
+	device := newAuthenticator(t)
+	register(t, app, device)
+
+	stored := users["user"].Credentials
+	if len(stored) != 1 || !bytes.Equal(stored[0].ID, device.credentialID) {
+		t.Fatalf("stored credentials = %+v", stored)
+	}
+
+	challenge, cookie := begin(t, app, "/login/begin")
+	if status, body := finish(t, app, "/login/finish", cookie, device.logIn(t, challenge, "user")); status != http.StatusOK || body != "user" {
+		t.Fatalf("log in = %d %s, want the user logged in", status, body)
+	}
+}
+
+func TestLogInIsRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		// answers the started ceremony, the cookie can be changed before it is sent
+		answer  func(t *testing.T, device *authenticator, challenge string, cookie **http.Cookie) []byte
+		wantErr string
+	}{
+		{
+			name: "without a started ceremony",
+			answer: func(t *testing.T, device *authenticator, challenge string, cookie **http.Cookie) []byte {
+				*cookie = nil
+				return device.logIn(t, challenge, "user")
+			},
+			wantErr: "has not been started",
+		},
+		{
+			name: "other challenge",
+			answer: func(t *testing.T, device *authenticator, challenge string, cookie **http.Cookie) []byte {
+				return device.logIn(t, encode([]byte("some other challenge value")), "user")
+			},
+			wantErr: "challenge",
+		},
+		{
+			name: "other origin",
+			answer: func(t *testing.T, device *authenticator, challenge string, cookie **http.Cookie) []byte {
+				device.origin = "https://evil.example.com"
+				return device.logIn(t, challenge, "user")
+			},
+			wantErr: "origin",
+		},
+		{
+			name: "unknown user handle",
+			answer: func(t *testing.T, device *authenticator, challenge string, cookie **http.Cookie) []byte {
+				return device.logIn(t, challenge, "someone else")
+			},
+			wantErr: "unknown user",
+		},
+		{
+			name: "signed by another key",
+			answer: func(t *testing.T, device *authenticator, challenge string, cookie **http.Cookie) []byte {
+				device.key = newAuthenticator(t).key
+				return device.logIn(t, challenge, "user")
+			},
+			wantErr: "signature",
+		},
+		{
+			// a counter which doesnt move forward means the key was cloned
+			name: "sign count going backwards",
+			answer: func(t *testing.T, device *authenticator, challenge string, cookie **http.Cookie) []byte {
+				device.signCount = 5
+				return device.logIn(t, challenge, "user")
+			},
+			wantErr: "cloned",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app, users := setup(t)
+			device := newAuthenticator(t)
+			register(t, app, device)
+			users["user"].Credentials[0].Authenticator.SignCount = 10
+
+			challenge, cookie := begin(t, app, "/login/begin")
+			body := test.answer(t, device, challenge, &cookie)
+			status, message := finish(t, app, "/login/finish", cookie, body)
+			if status != http.StatusBadRequest || !strings.Contains(message, test.wantErr) {
+				t.Errorf("log in = %d %s, want it rejected with %q", status, message, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestCeremonyCanOnlyBeUsedOnce(t *testing.T) {
+	app, _ := setup(t)
+	device := newAuthenticator(t)
+	register(t, app, device)
+
+	challenge, cookie := begin(t, app, "/login/begin")
+	body := device.logIn(t, challenge, "user")
+	if status, message := finish(t, app, "/login/finish", cookie, body); status != http.StatusOK {
+		t.Fatalf("log in = %d %s", status, message)
+	}
+	if status, _ := finish(t, app, "/login/finish", cookie, body); status != http.StatusBadRequest {
+		t.Errorf("replayed log in = %d, want it rejected", status)
+	}
+}
+
+func TestRegisteringTheSamePasskeyTwice(t *testing.T) {
+	app, _ := setup(t)
+	device := newAuthenticator(t)
+	register(t, app, device)
+
+	// the options exclude the registered passkey so browsers refuse it, the test checks the excluded id is sent
+	res, err := app.Test(httptest.NewRequest(http.MethodPost, "/register/begin", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(res.Body)
+	if !strings.Contains(string(data), encode(device.credentialID)) {
+		t.Errorf("registration options %s dont exclude the registered passkey", data)
+	}
+}
@@ -0,0 +1,144 @@
+package passwords
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+const (
+	saltLength = 16
+	keyLength  = 32
+)
+
+var errInvalidHash = errors.New("invalid password hash")
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// configs created before the hashing was configurable get argon2id with the defaults
+func algorithm() string {
+	if configs.Configs.Authentication.PasswordHashingAlgorithm == "" {
+		return Argon2id
+	}
+	return configs.Configs.Authentication.PasswordHashingAlgorithm
+}
+
+func configuredArgon2Params() argon2Params {
+	params := argon2Params{memory: 64 * 1024, iterations: 3, parallelism: 2}
+	if configs.Configs.Authentication.Argon2Memory > 0 {
+		params.memory = uint32(configs.Configs.Authentication.Argon2Memory)
+	}
+	if configs.Configs.Authentication.Argon2Iterations > 0 {
+		params.iterations = uint32(configs.Configs.Authentication.Argon2Iterations)
+	}
+	if configs.Configs.Authentication.Argon2Parallelism > 0 {
+		params.parallelism = uint8(configs.Configs.Authentication.Argon2Parallelism)
+	}
+	return params
+}
+
+func bcryptCost() int {
+	if configs.Configs.Authentication.BcryptCost == 0 {
+		return bcrypt.DefaultCost
+	}
+	return configs.Configs.Authentication.BcryptCost
+}
+
+// hashes the password with the configured algorithm, argon2id hashes are in the phc string format
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>, bcrypt hashes are in their own format
+func Hash(password string) (string, error) {
+	if algorithm() == Bcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost())
+		return string(hash), err
+	}
+
+	params := configuredArgon2Params()
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, keyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		params.memory,
+		params.iterations,
+		params.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// checks the password against a hash of either algorithm, users without a password never match
+func Verify(hash, password string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := decodeArgon2(hash)
+		if err != nil {
+			return false
+		}
+		derived := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+		return subtle.ConstantTimeCompare(derived, key) == 1
+	}
+	if strings.HasPrefix(hash, "$2") {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+	return false
+}
+
+// hashes made with another algorithm or other parameters than the configured ones should be replaced after a successful log in
+func NeedsRehash(hash string) bool {
+	if algorithm() == Bcrypt {
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost != bcryptCost()
+	}
+
+	params, _, key, err := decodeArgon2(hash)
+	if err != nil {
+		return true
+	}
+	return params != configuredArgon2Params() || len(key) != keyLength
+}
+
+func decodeArgon2(hash string) (argon2Params, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != Argon2id {
+		return argon2Params{}, nil, nil, errInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return argon2Params{}, nil, nil, errInvalidHash
+	}
+
+	var params argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return argon2Params{}, nil, nil, errInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return argon2Params{}, nil, nil, errInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return argon2Params{}, nil, nil, errInvalidHash
+	}
+
+	return params, salt, key, nil
+}
@@ -0,0 +1,133 @@
+package passwords
+
+import (
+	"testing"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func useConfig(t *testing.T, authentication configs.Authentication) {
+	t.Helper()
+	previous := configs.Configs.Authentication
+	configs.Configs.Authentication = authentication
+	t.Cleanup(func() { configs.Configs.Authentication = previous })
+}
+
+// small parameters keep the tests fast, the defaults are checked in TestDefaultsForOlderConfigs
+func fastArgon2() configs.Authentication {
+	return configs.Authentication{PasswordHashingAlgorithm: Argon2id, Argon2Memory: 1024, Argon2Iterations: 1, Argon2Parallelism: 1}
+}
+
+func mustHash(t *testing.T, password string) string {
+	t.Helper()
+	hash, err := Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestHashFormat(t *testing.T) {
+	useConfig(t, fastArgon2())
+	hash := mustHash(t, "hunter2")
+
+	params, salt, key, err := decodeArgon2(hash)
+	if err != nil {
+		t.Fatalf("decoding %q: %v", hash, err)
+	}
+	if params != (argon2Params{memory: 1024, iterations: 1, parallelism: 1}) {
+		t.Errorf("params = %+v", params)
+	}
+	if len(salt) != saltLength || len(key) != keyLength {
+		t.Errorf("salt is %d bytes and key %d bytes", len(salt), len(key))
+	}
+	if other := mustHash(t, "hunter2"); other == hash {
+		t.Error("two hashes of the same password have the same salt")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	useConfig(t, fastArgon2())
+	argonHash := mustHash(t, "hunter2")
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		want     bool
+	}{
+		{"argon2id right password", argonHash, "hunter2", true},
+		{"argon2id wrong password", argonHash, "hunter3", false},
+		{"bcrypt right password", string(bcryptHash), "hunter2", true},
+		{"bcrypt wrong password", string(bcryptHash), "hunter3", false},
+		{"no password", "", "", false},
+		{"plain text is never a hash", "hunter2", "hunter2", false},
+		{"other phc algorithm", "$argon2i$v=19$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5", "hunter2", false},
+		{"wrong version", "$argon2id$v=16$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5", "hunter2", false},
+		{"broken params", "$argon2id$v=19$m=x,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5", "hunter2", false},
+		{"missing part", "$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA", "hunter2", false},
+		{"empty key", "$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$", "hunter2", false},
+		{"salt not base64", "$argon2id$v=19$m=1024,t=1,p=1$!!!$a2V5", "hunter2", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Verify(test.hash, test.password); got != test.want {
+				t.Errorf("Verify(%q, %q) = %v, want %v", test.hash, test.password, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	useConfig(t, fastArgon2())
+	current := mustHash(t, "hunter2")
+	useConfig(t, configs.Authentication{PasswordHashingAlgorithm: Argon2id, Argon2Memory: 2048, Argon2Iterations: 1, Argon2Parallelism: 1})
+	otherMemory := mustHash(t, "hunter2")
+	useConfig(t, configs.Authentication{PasswordHashingAlgorithm: Bcrypt, BcryptCost: bcrypt.MinCost})
+	bcryptHash := mustHash(t, "hunter2")
+	useConfig(t, configs.Authentication{PasswordHashingAlgorithm: Bcrypt, BcryptCost: bcrypt.MinCost + 1})
+	otherCost := mustHash(t, "hunter2")
+
+	tests := []struct {
+		name   string
+		config configs.Authentication
+		hash   string
+		want   bool
+	}{
+		{"argon2id with the configured params", fastArgon2(), current, false},
+		{"argon2id with other params", fastArgon2(), otherMemory, true},
+		{"bcrypt when argon2id is configured", fastArgon2(), bcryptHash, true},
+		{"broken hash", fastArgon2(), "$argon2id$v=19$m=1024", true},
+		{"bcrypt with the configured cost", configs.Authentication{PasswordHashingAlgorithm: Bcrypt, BcryptCost: bcrypt.MinCost}, bcryptHash, false},
+		{"bcrypt with another cost", configs.Authentication{PasswordHashingAlgorithm: Bcrypt, BcryptCost: bcrypt.MinCost}, otherCost, true},
+		{"argon2id when bcrypt is configured", configs.Authentication{PasswordHashingAlgorithm: Bcrypt, BcryptCost: bcrypt.MinCost}, current, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useConfig(t, test.config)
+			if got := NeedsRehash(test.hash); got != test.want {
+				t.Errorf("NeedsRehash(%q) = %v, want %v", test.hash, got, test.want)
+			}
+		})
+	}
+}
+
+func TestDefaultsForOlderConfigs(t *testing.T) {
+	useConfig(t, configs.Authentication{})
+	if algorithm() != Argon2id {
+		t.Errorf("algorithm() = %q, want %q", algorithm(), Argon2id)
+	}
+	if params := configuredArgon2Params(); params != (argon2Params{memory: 64 * 1024, iterations: 3, parallelism: 2}) {
+		t.Errorf("configuredArgon2Params() = %+v", params)
+	}
+	if bcryptCost() != bcrypt.DefaultCost {
+		t.Errorf("bcryptCost() = %d, want %d", bcryptCost(), bcrypt.DefaultCost)
+	}
+}
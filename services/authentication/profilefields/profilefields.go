@@ -0,0 +1,57 @@
+package profilefields
+
+import (
+	"errors"
+	"math"
+	"unicode/utf8"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+)
+
+func find(name string) (configs.ProfileField, bool) {
+	for _, field := range configs.Configs.ProfileFields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return configs.ProfileField{}, false
+}
+
+// checks the fields of an update against the configured ones, a null value removes the field from the user
+// json numbers are decoded as float64 so integers are checked to have no fraction
+func Check(fields map[string]any) error {
+	for name, value := range fields {
+		field, ok := find(name)
+		if !ok {
+			return errors.New("unknown profile field: " + name)
+		}
+		if value == nil {
+			continue
+		}
+
+		switch field.Type {
+		case "string":
+			text, ok := value.(string)
+			if !ok {
+				return errors.New(name + " has to be a string")
+			}
+			if field.MaxLength > 0 && utf8.RuneCountInString(text) > field.MaxLength {
+				return errors.New(name + " is longer than the allowed length")
+			}
+		case "number":
+			if _, ok := value.(float64); !ok {
+				return errors.New(name + " has to be a number")
+			}
+		case "integer":
+			number, ok := value.(float64)
+			if !ok || number != math.Trunc(number) {
+				return errors.New(name + " has to be an integer")
+			}
+		case "boolean":
+			if _, ok := value.(bool); !ok {
+				return errors.New(name + " has to be true or false")
+			}
+		}
+	}
+	return nil
+}
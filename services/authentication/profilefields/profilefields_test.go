@@ -0,0 +1,50 @@
+package profilefields
+
+import (
+	"testing"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+)
+
+func TestCheck(t *testing.T) {
+	previous := configs.Configs.ProfileFields
+	configs.Configs.ProfileFields = []configs.ProfileField{
+		{Name: "bio", Type: "string", MaxLength: 5},
+		{Name: "nickname", Type: "string"},
+		{Name: "height", Type: "number"},
+		{Name: "age", Type: "integer"},
+		{Name: "newsletter", Type: "boolean"},
+	}
+	t.Cleanup(func() { configs.Configs.ProfileFields = previous })
+
+	// values are checked the way encoding/json decodes them into a map
+	tests := []struct {
+		name    string
+		fields  map[string]any
+		wantErr bool
+	}{
+		{"no fields", map[string]any{}, false},
+		{"every type", map[string]any{"bio": "hi", "height": 1.8, "age": 30.0, "newsletter": true}, false},
+		{"unknown field", map[string]any{"shoeSize": 42.0}, true},
+		{"null removes a field", map[string]any{"bio": nil, "age": nil}, false},
+		{"string at the max length", map[string]any{"bio": "hello"}, false},
+		{"string over the max length", map[string]any{"bio": "hello!"}, true},
+		{"length is counted in characters", map[string]any{"bio": "héllo"}, false},
+		{"string without max length", map[string]any{"nickname": "a very long nickname indeed"}, false},
+		{"number as string", map[string]any{"bio": 5.0}, true},
+		{"string as number", map[string]any{"height": "1.8"}, true},
+		{"integer", map[string]any{"age": -3.0}, false},
+		{"integer with a fraction", map[string]any{"age": 30.5}, true},
+		{"integer as boolean", map[string]any{"age": true}, true},
+		{"boolean as string", map[string]any{"newsletter": "true"}, true},
+		{"one invalid field fails the update", map[string]any{"bio": "hi", "age": "thirty"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := Check(test.fields); (err != nil) != test.wantErr {
+				t.Errorf("Check(%v) error = %v, wantErr %v", test.fields, err, test.wantErr)
+			}
+		})
+	}
+}
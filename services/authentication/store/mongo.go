@@ -0,0 +1,429 @@
+package store
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// collections which belong to a user and are removed together with it
+var mongoUserCollections = []string{"two_factor", "passkeys", "password_resets", "api_keys"}
+
+type MongoStore struct {
+	database *mongo.Database
+}
+
+func NewMongoStore(mongoClient *mongo.Client) *MongoStore {
+	return &MongoStore{database: mongoClient.Database("mooshroombase")}
+}
+
+func (s *MongoStore) users() *mongo.Collection {
+	return s.database.Collection("users")
+}
+
+func mongoError(err error) error {
+	if err == mongo.ErrNoDocuments {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *MongoStore) CreateUser(user types.AuthUser) error {
+	if user.RawData == nil {
+		user.RawData = []types.RawUserData{}
+	}
+	if user.OAuthAccounts == nil {
+		user.OAuthAccounts = []types.OAuthAccount{}
+	}
+	// a null document cant get fields set inside it later
+	if user.ProfileFields == nil {
+		user.ProfileFields = map[string]any{}
+	}
+	_, err := s.users().InsertOne(context.Background(), user)
+	return err
+}
+
+func (s *MongoStore) findUser(filter bson.M) (types.AuthUser, error) {
+	var user types.AuthUser
+	err := s.users().FindOne(context.Background(), filter).Decode(&user)
+	return user, mongoError(err)
+}
+
+func (s *MongoStore) FindUserByID(id string) (types.AuthUser, error) {
+	return s.findUser(bson.M{"id": id})
+}
+
+func (s *MongoStore) FindUserByEmail(email string) (types.AuthUser, error) {
+	return s.findUser(bson.M{"email": email})
+}
+
+func (s *MongoStore) FindUserByUserName(username string) (types.AuthUser, error) {
+	return s.findUser(bson.M{"username": username})
+}
+
+func (s *MongoStore) FindUserByOAuthAccount(provider, providerUserID string) (types.AuthUser, error) {
+	return s.findUser(bson.M{"oauthAccounts": bson.M{"$elemMatch": bson.M{"provider": provider, "providerUserId": providerUserID}}})
+}
+
+// profile fields are set one by one so the fields which arent in the update stay
+func mongoUpdate(update UserUpdate) bson.M {
+	set := bson.M{"updatedAt": time.Now()}
+	unset := bson.M{}
+	if update.UserName != nil {
+		set["username"] = *update.UserName
+	}
+	if update.FirstName != nil {
+		set["firstName"] = *update.FirstName
+	}
+	if update.LastName != nil {
+		set["lastName"] = *update.LastName
+	}
+	if update.Email != nil {
+		set["email"] = *update.Email
+	}
+	if update.Password != nil {
+		set["password"] = *update.Password
+	}
+	if update.ProfilePicture != nil {
+		set["profilePicture"] = *update.ProfilePicture
+	}
+	if update.Verified != nil {
+		set["verified"] = *update.Verified
+	}
+	if update.VerificationToken != nil {
+		set["verificationToken"] = *update.VerificationToken
+	}
+	if update.LastLoggedIn != nil {
+		set["lastLoggedIn"] = types.LastTimeLoggedIn{When: *update.LastLoggedIn}
+	}
+	if update.Disabled != nil {
+		set["disabled"] = *update.Disabled
+		set["disabledReason"] = update.DisabledReason
+	}
+	if update.RawData != nil {
+		set["rawData"] = update.RawData
+	}
+	if update.Roles != nil {
+		set["roles"] = update.Roles
+	}
+	for name, value := range update.ProfileFields {
+		if value == nil {
+			unset["profileFields."+name] = ""
+		} else {
+			set["profileFields."+name] = value
+		}
+	}
+
+	document := bson.M{"$set": set}
+	if len(unset) > 0 {
+		document["$unset"] = unset
+	}
+	return document
+}
+
+func (s *MongoStore) UpdateUser(id string, update UserUpdate) error {
+	result, err := s.users().UpdateOne(context.Background(), bson.M{"id": id}, mongoUpdate(update))
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *MongoStore) deleteUser(filter bson.M) (bool, error) {
+	var user struct {
+		ID string `bson:"id"`
+	}
+	err := s.users().FindOneAndDelete(context.Background(), filter, options.FindOneAndDelete().SetProjection(bson.M{"id": 1})).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, collection := range mongoUserCollections {
+		if _, err := s.database.Collection(collection).DeleteMany(context.Background(), bson.M{"userId": user.ID}); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// removes the user together with their two factor, passkeys, password reset tokens and api keys
+func (s *MongoStore) DeleteUser(id string) error {
+	deleted, err := s.deleteUser(bson.M{"id": id})
+	if err == nil && !deleted {
+		return ErrNotFound
+	}
+	return err
+}
+
+// users newest first, the search matches a part of the email or the username
+func (s *MongoStore) ListUsers(search string, page, limit int) ([]types.AuthUser, int64, error) {
+	filter := bson.M{}
+	if search != "" {
+		pattern := bson.M{"$regex": regexp.QuoteMeta(search), "$options": "i"}
+		filter = bson.M{"$or": bson.A{bson.M{"email": pattern}, bson.M{"username": pattern}}}
+	}
+
+	total, err := s.users().CountDocuments(context.Background(), filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.M{"createdAt": -1}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := s.users().Find(context.Background(), filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	users := []types.AuthUser{}
+	err = cursor.All(context.Background(), &users)
+	return users, total, err
+}
+
+// users without a roles field get the default roles when their token is issued
+func (s *MongoStore) FindRoles(userID string) ([]string, error) {
+	var user struct {
+		Roles []string `bson:"roles"`
+	}
+	err := s.users().FindOne(context.Background(), bson.M{"id": userID}, options.FindOne().SetProjection(bson.M{"roles": 1})).Decode(&user)
+	return user.Roles, mongoError(err)
+}
+
+func (s *MongoStore) IsDisabled(userID string) (bool, error) {
+	var user struct {
+		Disabled bool `bson:"disabled"`
+	}
+	err := s.users().FindOne(context.Background(), bson.M{"id": userID}, options.FindOne().SetProjection(bson.M{"disabled": 1})).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	return user.Disabled, err
+}
+
+func (s *MongoStore) AppendRawData(userID string, data types.RawUserData) error {
+	result, err := s.users().UpdateOne(context.Background(), bson.M{"id": userID}, bson.M{"$push": bson.M{"rawData": data}, "$set": bson.M{"updatedAt": time.Now()}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// sends the user once and again after every change of the users collection until ctx is done or send fails
+func (s *MongoStore) WatchUser(ctx context.Context, userID string, send func(user types.AuthUser) error) error {
+	user, err := s.FindUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := s.users().Watch(ctx, mongo.Pipeline{})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	if err := send(user); err != nil {
+		return err
+	}
+
+	for cursor.Next(ctx) {
+		user, err := s.FindUserByID(userID)
+		if err != nil {
+			return err
+		}
+		if err := send(user); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+func (s *MongoStore) UpgradeGuest(userID string, update UserUpdate) error {
+	document := mongoUpdate(update)
+	document["$set"].(bson.M)["guest"] = false
+	result, err := s.users().UpdateOne(context.Background(), bson.M{"id": userID, "guest": true}, document)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *MongoStore) ExpiredGuests(createdBefore time.Time) ([]string, error) {
+	cursor, err := s.users().Find(context.Background(), bson.M{"guest": true, "createdAt": bson.M{"$lt": createdBefore}}, options.Find().SetProjection(bson.M{"id": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var guests []struct {
+		ID string `bson:"id"`
+	}
+	if err := cursor.All(context.Background(), &guests); err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+	for _, guest := range guests {
+		ids = append(ids, guest.ID)
+	}
+	return ids, nil
+}
+
+// the guest flag is checked again in case the guest upgraded in the meantime
+func (s *MongoStore) DeleteGuest(userID string) (bool, error) {
+	return s.deleteUser(bson.M{"id": userID, "guest": true})
+}
+
+func (s *MongoStore) LinkOAuthAccount(userID string, account types.OAuthAccount) error {
+	_, err := s.users().UpdateOne(context.Background(), bson.M{"id": userID}, bson.M{"$push": bson.M{"oauthAccounts": account}, "$set": bson.M{"updatedAt": time.Now()}})
+	return err
+}
+
+func (s *MongoStore) UnlinkOAuthAccount(userID, provider string) error {
+	_, err := s.users().UpdateOne(context.Background(), bson.M{"id": userID}, bson.M{"$pull": bson.M{"oauthAccounts": bson.M{"provider": provider}}, "$set": bson.M{"updatedAt": time.Now()}})
+	return err
+}
+
+func (s *MongoStore) FindTwoFactor(userID string) (types.TwoFactor, error) {
+	var twoFactor types.TwoFactor
+	err := s.database.Collection("two_factor").FindOne(context.Background(), bson.M{"userId": userID}).Decode(&twoFactor)
+	return twoFactor, mongoError(err)
+}
+
+// replaces the secret of an earlier enrollment, it stays disabled until EnableTwoFactor
+func (s *MongoStore) SaveTwoFactorSecret(userID, secret string) error {
+	_, err := s.database.Collection("two_factor").ReplaceOne(context.Background(), bson.M{"userId": userID}, types.TwoFactor{
+		UserID:        userID,
+		Secret:        secret,
+		Enabled:       false,
+		RecoveryCodes: []string{},
+		CreatedAt:     time.Now(),
+	}, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (s *MongoStore) EnableTwoFactor(userID string, recoveryCodeHashes []string) error {
+	_, err := s.database.Collection("two_factor").UpdateOne(context.Background(), bson.M{"userId": userID}, bson.M{"$set": bson.M{"enabled": true, "recoveryCodes": recoveryCodeHashes}})
+	return err
+}
+
+// removes the recovery code, it tells if the code was one of the user's
+func (s *MongoStore) UseRecoveryCode(userID, codeHash string) (bool, error) {
+	result, err := s.database.Collection("two_factor").UpdateOne(context.Background(), bson.M{"userId": userID, "recoveryCodes": codeHash}, bson.M{"$pull": bson.M{"recoveryCodes": codeHash}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (s *MongoStore) DeleteTwoFactor(userID string) error {
+	_, err := s.database.Collection("two_factor").DeleteOne(context.Background(), bson.M{"userId": userID})
+	return err
+}
+
+func (s *MongoStore) FindPasskeys(userID string) ([]types.Passkey, error) {
+	cursor, err := s.database.Collection("passkeys").Find(context.Background(), bson.M{"userId": userID})
+	if err != nil {
+		return nil, err
+	}
+
+	passkeys := []types.Passkey{}
+	err = cursor.All(context.Background(), &passkeys)
+	return passkeys, err
+}
+
+func (s *MongoStore) CreatePasskey(passkey types.Passkey) error {
+	_, err := s.database.Collection("passkeys").InsertOne(context.Background(), passkey)
+	return err
+}
+
+func (s *MongoStore) UpdatePasskeyUsage(userID, id string, signCount uint32, backupState bool) error {
+	_, err := s.database.Collection("passkeys").UpdateOne(context.Background(),
+		bson.M{"id": id, "userId": userID},
+		bson.M{"$set": bson.M{"signCount": signCount, "backupState": backupState, "lastUsedAt": time.Now()}},
+	)
+	return err
+}
+
+func (s *MongoStore) DeletePasskey(userID, id string) error {
+	result, err := s.database.Collection("passkeys").DeleteOne(context.Background(), bson.M{"id": id, "userId": userID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *MongoStore) CreatePasswordReset(reset types.PasswordReset) error {
+	_, err := s.database.Collection("password_resets").InsertOne(context.Background(), reset)
+	return err
+}
+
+// deleting the token while reading it makes sure it can only be used once
+func (s *MongoStore) TakePasswordReset(tokenHash string) (types.PasswordReset, error) {
+	var reset types.PasswordReset
+	err := s.database.Collection("password_resets").FindOneAndDelete(context.Background(), bson.M{"tokenHash": tokenHash}).Decode(&reset)
+	return reset, mongoError(err)
+}
+
+func (s *MongoStore) DeletePasswordResets(userID string) error {
+	_, err := s.database.Collection("password_resets").DeleteMany(context.Background(), bson.M{"userId": userID})
+	return err
+}
+
+func (s *MongoStore) CreateAPIKey(apiKey types.APIKey) error {
+	_, err := s.database.Collection("api_keys").InsertOne(context.Background(), apiKey)
+	return err
+}
+
+func (s *MongoStore) ListAPIKeys() ([]types.APIKey, error) {
+	cursor, err := s.database.Collection("api_keys").Find(context.Background(), bson.M{}, options.Find().SetSort(bson.M{"createdAt": -1}))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []types.APIKey{}
+	err = cursor.All(context.Background(), &keys)
+	return keys, err
+}
+
+func (s *MongoStore) DeleteAPIKey(id string) error {
+	result, err := s.database.Collection("api_keys").DeleteOne(context.Background(), bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *MongoStore) FindAPIKeyByHash(keyHash string) (types.APIKey, error) {
+	var apiKey types.APIKey
+	err := s.database.Collection("api_keys").FindOne(context.Background(), bson.M{"keyHash": keyHash}).Decode(&apiKey)
+	return apiKey, mongoError(err)
+}
+
+func (s *MongoStore) MarkAPIKeyUsed(id string, usedAt time.Time) error {
+	_, err := s.database.Collection("api_keys").UpdateOne(context.Background(), bson.M{"id": id}, bson.M{"$set": bson.M{"lastUsedAt": usedAt}})
+	return err
+}
@@ -0,0 +1,837 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/types"
+)
+
+// disabled users and guests live in their own tables so they are joined to every user query
+const sqlUserQuery = `SELECT u.ID, u.UserName, u.FirstName, u.LastName, u.Email, u.Password, COALESCE(u.ProfilePicture, ''), u.CreatedAt, u.UpdatedAt, u.Verified, COALESCE(u.VerificationToken, ''), u.LastLoggedIn,
+  d.UserID IS NOT NULL, COALESCE(d.Reason, ''), g.UserID IS NOT NULL
+  FROM mooshroombase.users u
+  LEFT JOIN mooshroombase.disabled_users d ON d.UserID = u.ID
+  LEFT JOIN mooshroombase.guest_users g ON g.UserID = u.ID`
+
+// the columns every api key query selects, scanned by scanSQLAPIKey
+const sqlAPIKeyColumns = `ID, Name, KeyHash, Prefix, Scopes, UserID, CreatedAt, ExpiresAt, LastUsedAt`
+
+// the parts of the queries which differ between the sql databases
+type dialect int
+
+const (
+	mariaDialect dialect = iota
+	postgresDialect
+	sqliteDialect
+)
+
+// the same store is used for mariadb, postgres and sqlite, the queries are written for mariadb and adjusted by the dialect
+type SQLStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+func NewMariaStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, dialect: mariaDialect}
+}
+
+// postgres has the tables in the mooshroombase schema so the queries dont change
+func NewPostgresStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, dialect: postgresDialect}
+}
+
+// sqlite keeps the tables in the database file itself so they have no schema
+func NewSQLiteStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, dialect: sqliteDialect}
+}
+
+// queries are written with ? placeholders and the mooshroombase schema
+// postgres numbers the placeholders as $1, $2... and sqlite tables have no schema
+func (s *SQLStore) q(query string) string {
+	switch s.dialect {
+	case sqliteDialect:
+		return strings.ReplaceAll(query, "mooshroombase.", "")
+	case mariaDialect:
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// clause of an insert which updates the row already using the key, the columns get the values of the insert
+func (s *SQLStore) onConflict(key string, columns ...string) string {
+	assignments := make([]string, len(columns))
+	for i, column := range columns {
+		if s.dialect != mariaDialect {
+			assignments[i] = column + " = EXCLUDED." + column
+		} else {
+			assignments[i] = column + " = VALUES(" + column + ")"
+		}
+	}
+
+	if s.dialect != mariaDialect {
+		return " ON CONFLICT (" + key + ") DO UPDATE SET " + strings.Join(assignments, ", ")
+	}
+	return " ON DUPLICATE KEY UPDATE " + strings.Join(assignments, ", ")
+}
+
+func sqlError(err error) error {
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSQLUser(row scanner) (types.AuthUser, error) {
+	var user types.AuthUser
+	var lastLoggedIn sql.NullTime
+	err := row.Scan(
+		&user.ID,
+		&user.UserName,
+		&user.FirstName,
+		&user.LastName,
+		&user.Email,
+		&user.Password,
+		&user.ProfilePicture,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.Verified,
+		&user.VerificationToken,
+		&lastLoggedIn,
+		&user.Disabled,
+		&user.DisabledReason,
+		&user.Guest,
+	)
+	user.LastLoggedIn = types.LastTimeLoggedIn{When: lastLoggedIn.Time}
+	return user, err
+}
+
+// oauth accounts, roles, raw data and profile fields are in their own tables, they are loaded for all users at once
+// so a page of users costs one query per table
+func (s *SQLStore) loadRelations(users []types.AuthUser) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	byID := map[string]*types.AuthUser{}
+	ids := []any{}
+	for i := range users {
+		users[i].OAuthAccounts = []types.OAuthAccount{}
+		users[i].Roles = []string{}
+		users[i].RawData = []types.RawUserData{}
+		users[i].ProfileFields = map[string]any{}
+		byID[users[i].ID] = &users[i]
+		ids = append(ids, users[i].ID)
+	}
+	in := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ") + ")"
+
+	err := s.eachRow(`SELECT UserID, Provider, ProviderUserID, Email, LinkedAt FROM mooshroombase.oauth_accounts WHERE UserID IN `+in, ids, func(rows *sql.Rows) error {
+		var userID string
+		var account types.OAuthAccount
+		var email sql.NullString
+		if err := rows.Scan(&userID, &account.Provider, &account.ProviderUserID, &email, &account.LinkedAt); err != nil {
+			return err
+		}
+		account.Email = email.String
+		byID[userID].OAuthAccounts = append(byID[userID].OAuthAccounts, account)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.eachRow(`SELECT UserID, Role FROM mooshroombase.user_roles WHERE UserID IN `+in, ids, func(rows *sql.Rows) error {
+		var userID, role string
+		if err := rows.Scan(&userID, &role); err != nil {
+			return err
+		}
+		byID[userID].Roles = append(byID[userID].Roles, role)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// raw data is kept in the order it was appended
+	err = s.eachRow(`SELECT UserID, Data FROM mooshroombase.user_raw_data WHERE UserID IN `+in+` ORDER BY ID`, ids, func(rows *sql.Rows) error {
+		var userID string
+		var data []byte
+		if err := rows.Scan(&userID, &data); err != nil {
+			return err
+		}
+		entry := types.RawUserData{}
+		if err := json.Unmarshal(data, &entry.Data); err != nil {
+			return err
+		}
+		byID[userID].RawData = append(byID[userID].RawData, entry)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.eachRow(`SELECT UserID, Name, Value FROM mooshroombase.user_profile_fields WHERE UserID IN `+in, ids, func(rows *sql.Rows) error {
+		var userID, name string
+		var value []byte
+		if err := rows.Scan(&userID, &name, &value); err != nil {
+			return err
+		}
+		var field any
+		if err := json.Unmarshal(value, &field); err != nil {
+			return err
+		}
+		byID[userID].ProfileFields[name] = field
+		return nil
+	})
+}
+
+func (s *SQLStore) eachRow(query string, args []any, scan func(rows *sql.Rows) error) error {
+	rows, err := s.db.Query(s.q(query), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// the values are sent as json text, postgres would take bytes for bytea
+func (s *SQLStore) appendRawData(tx *sql.Tx, userID string, data types.RawUserData) error {
+	encoded, err := json.Marshal(data.Data)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(s.q(`INSERT INTO mooshroombase.user_raw_data (UserID, Data) VALUES (?, ?)`), userID, string(encoded))
+	return err
+}
+
+func (s *SQLStore) findUser(where string, args ...any) (types.AuthUser, error) {
+	user, err := scanSQLUser(s.db.QueryRow(s.q(sqlUserQuery+` WHERE `+where), args...))
+	if err != nil {
+		return user, sqlError(err)
+	}
+	users := []types.AuthUser{user}
+	if err := s.loadRelations(users); err != nil {
+		return user, err
+	}
+	return users[0], nil
+}
+
+func (s *SQLStore) CreateUser(user types.AuthUser) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(s.q(`
+    INSERT INTO mooshroombase.users (
+        ID,
+        UserName,
+        FirstName,
+        LastName,
+        Email,
+        Password,
+        ProfilePicture,
+        CreatedAt,
+        UpdatedAt,
+        Verified,
+        VerificationToken,
+        LastLoggedIn
+    ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`),
+		user.ID,
+		user.UserName,
+		user.FirstName,
+		user.LastName,
+		user.Email,
+		user.Password,
+		user.ProfilePicture,
+		user.CreatedAt,
+		user.UpdatedAt,
+		user.Verified,
+		user.VerificationToken,
+		user.LastLoggedIn.When,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, account := range user.OAuthAccounts {
+		if err := s.linkOAuthAccount(tx, user.ID, account); err != nil {
+			return err
+		}
+	}
+	if err := s.setRoles(tx, user.ID, user.Roles); err != nil {
+		return err
+	}
+	if user.Guest {
+		if _, err := tx.Exec(s.q(`INSERT INTO mooshroombase.guest_users (UserID, CreatedAt) VALUES (?, ?)`), user.ID, user.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLStore) FindUserByID(id string) (types.AuthUser, error) {
+	return s.findUser(`u.ID = ?`, id)
+}
+
+func (s *SQLStore) FindUserByEmail(email string) (types.AuthUser, error) {
+	return s.findUser(`u.Email = ?`, email)
+}
+
+func (s *SQLStore) FindUserByUserName(username string) (types.AuthUser, error) {
+	return s.findUser(`u.UserName = ?`, username)
+}
+
+func (s *SQLStore) FindUserByOAuthAccount(provider, providerUserID string) (types.AuthUser, error) {
+	return s.findUser(`u.ID = (SELECT UserID FROM mooshroombase.oauth_accounts WHERE Provider = ? AND ProviderUserID = ?)`, provider, providerUserID)
+}
+
+// the columns are fixed names so only the values are passed as arguments
+func (s *SQLStore) applyUpdate(tx *sql.Tx, id string, update UserUpdate) error {
+	columns := []string{"UpdatedAt = ?"}
+	args := []any{time.Now()}
+	set := func(column string, value any) {
+		columns = append(columns, column+" = ?")
+		args = append(args, value)
+	}
+
+	if update.UserName != nil {
+		set("UserName", *update.UserName)
+	}
+	if update.FirstName != nil {
+		set("FirstName", *update.FirstName)
+	}
+	if update.LastName != nil {
+		set("LastName", *update.LastName)
+	}
+	if update.Email != nil {
+		set("Email", *update.Email)
+	}
+	if update.Password != nil {
+		set("Password", *update.Password)
+	}
+	if update.ProfilePicture != nil {
+		set("ProfilePicture", *update.ProfilePicture)
+	}
+	if update.Verified != nil {
+		set("Verified", *update.Verified)
+	}
+	if update.VerificationToken != nil {
+		set("VerificationToken", *update.VerificationToken)
+	}
+	if update.LastLoggedIn != nil {
+		set("LastLoggedIn", *update.LastLoggedIn)
+	}
+
+	if _, err := tx.Exec(s.q(`UPDATE mooshroombase.users SET `+strings.Join(columns, ", ")+` WHERE ID = ?`), append(args, id)...); err != nil {
+		return err
+	}
+
+	if update.Disabled != nil {
+		var err error
+		if *update.Disabled {
+			_, err = tx.Exec(s.q(`INSERT INTO mooshroombase.disabled_users (UserID, Reason) VALUES (?, ?)`+s.onConflict("UserID", "Reason")), id, update.DisabledReason)
+		} else {
+			_, err = tx.Exec(s.q(`DELETE FROM mooshroombase.disabled_users WHERE UserID = ?`), id)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if update.RawData != nil {
+		if _, err := tx.Exec(s.q(`DELETE FROM mooshroombase.user_raw_data WHERE UserID = ?`), id); err != nil {
+			return err
+		}
+		for _, data := range update.RawData {
+			if err := s.appendRawData(tx, id, data); err != nil {
+				return err
+			}
+		}
+	}
+
+	for name, value := range update.ProfileFields {
+		var err error
+		if value == nil {
+			_, err = tx.Exec(s.q(`DELETE FROM mooshroombase.user_profile_fields WHERE UserID = ? AND Name = ?`), id, name)
+		} else {
+			var encoded []byte
+			if encoded, err = json.Marshal(value); err != nil {
+				return err
+			}
+			_, err = tx.Exec(s.q(`INSERT INTO mooshroombase.user_profile_fields (UserID, Name, Value) VALUES (?, ?, ?)`+s.onConflict("UserID, Name", "Value")), id, name, string(encoded))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if update.Roles != nil {
+		if _, err := tx.Exec(s.q(`DELETE FROM mooshroombase.user_roles WHERE UserID = ?`), id); err != nil {
+			return err
+		}
+		if err := s.setRoles(tx, id, update.Roles); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SQLStore) updateUser(id string, update UserUpdate, existsQuery string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRow(s.q(existsQuery), id).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+
+	if err := s.applyUpdate(tx, id, update); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) UpdateUser(id string, update UserUpdate) error {
+	return s.updateUser(id, update, `SELECT COUNT(*) FROM mooshroombase.users WHERE ID = ?`)
+}
+
+// the tables of the user are removed by their foreign keys
+func (s *SQLStore) DeleteUser(id string) error {
+	result, err := s.db.Exec(s.q(`DELETE FROM mooshroombase.users WHERE ID = ?`), id)
+	if err != nil {
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// users newest first, the search matches a part of the email or the username ignoring the case
+func (s *SQLStore) ListUsers(search string, page, limit int) ([]types.AuthUser, int64, error) {
+	where, args := "", []any{}
+	if search != "" {
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(strings.ToLower(search)) + "%"
+		like := " LIKE ?"
+		if s.dialect == sqliteDialect {
+			// sqlite has no escape character unless it is given
+			like = ` LIKE ? ESCAPE '\'`
+		}
+		where, args = " WHERE LOWER(u.Email)"+like+" OR LOWER(u.UserName)"+like, []any{pattern, pattern}
+	}
+
+	var total int64
+	if err := s.db.QueryRow(s.q(`SELECT COUNT(*) FROM mooshroombase.users u`+where), args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.Query(s.q(sqlUserQuery+where+` ORDER BY u.CreatedAt DESC LIMIT ? OFFSET ?`), append(args, limit, (page-1)*limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []types.AuthUser{}
+	for rows.Next() {
+		user, err := scanSQLUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	if err := s.loadRelations(users); err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// users without rows get the default roles when their token is issued
+func (s *SQLStore) FindRoles(userID string) ([]string, error) {
+	rows, err := s.db.Query(s.q(`SELECT Role FROM mooshroombase.user_roles WHERE UserID = ?`), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []string{}
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func (s *SQLStore) IsDisabled(userID string) (bool, error) {
+	var count int
+	err := s.db.QueryRow(s.q(`SELECT COUNT(*) FROM mooshroombase.disabled_users WHERE UserID = ?`), userID).Scan(&count)
+	return count > 0, err
+}
+
+func (s *SQLStore) AppendRawData(userID string, data types.RawUserData) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRow(s.q(`SELECT COUNT(*) FROM mooshroombase.users WHERE ID = ?`), userID).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.Exec(s.q(`UPDATE mooshroombase.users SET UpdatedAt = ? WHERE ID = ?`), time.Now(), userID); err != nil {
+		return err
+	}
+	if err := s.appendRawData(tx, userID, data); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) WatchUser(ctx context.Context, userID string, send func(user types.AuthUser) error) error {
+	return ErrNotSupported
+}
+
+// the update and the removal of the guest row happen in one transaction
+func (s *SQLStore) UpgradeGuest(userID string, update UserUpdate) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(s.q(`DELETE FROM mooshroombase.guest_users WHERE UserID = ?`), userID)
+	if err != nil {
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err != nil || deleted == 0 {
+		return ErrNotFound
+	}
+
+	if err := s.applyUpdate(tx, userID, update); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) ExpiredGuests(createdBefore time.Time) ([]string, error) {
+	rows, err := s.db.Query(s.q(`SELECT UserID FROM mooshroombase.guest_users WHERE CreatedAt < ?`), createdBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	guests := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		guests = append(guests, userID)
+	}
+	return guests, rows.Err()
+}
+
+// the guest table is checked again in case the guest upgraded in the meantime, the other tables are cleaned up by their foreign keys
+func (s *SQLStore) DeleteGuest(userID string) (bool, error) {
+	result, err := s.db.Exec(s.q(`DELETE FROM mooshroombase.users WHERE ID = ? AND ID IN (SELECT UserID FROM mooshroombase.guest_users)`), userID)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+func (s *SQLStore) linkOAuthAccount(tx *sql.Tx, userID string, account types.OAuthAccount) error {
+	_, err := tx.Exec(s.q(`INSERT INTO mooshroombase.oauth_accounts (Provider, ProviderUserID, UserID, Email, LinkedAt) VALUES (?, ?, ?, ?, ?)`), account.Provider, account.ProviderUserID, userID, account.Email, account.LinkedAt)
+	return err
+}
+
+func (s *SQLStore) setRoles(tx *sql.Tx, userID string, roles []string) error {
+	for _, role := range roles {
+		if _, err := tx.Exec(s.q(`INSERT INTO mooshroombase.user_roles (UserID, Role) VALUES (?, ?)`), userID, role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLStore) LinkOAuthAccount(userID string, account types.OAuthAccount) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.linkOAuthAccount(tx, userID, account); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) UnlinkOAuthAccount(userID, provider string) error {
+	_, err := s.db.Exec(s.q(`DELETE FROM mooshroombase.oauth_accounts WHERE UserID = ? AND Provider = ?`), userID, provider)
+	return err
+}
+
+// recovery codes live in their own table so they are not loaded here
+func (s *SQLStore) FindTwoFactor(userID string) (types.TwoFactor, error) {
+	var twoFactor types.TwoFactor
+	err := s.db.QueryRow(s.q(`SELECT UserID, Secret, Enabled, CreatedAt FROM mooshroombase.two_factor WHERE UserID = ?`), userID).Scan(&twoFactor.UserID, &twoFactor.Secret, &twoFactor.Enabled, &twoFactor.CreatedAt)
+	return twoFactor, sqlError(err)
+}
+
+// replaces the secret of an earlier enrollment, it stays disabled until EnableTwoFactor
+func (s *SQLStore) SaveTwoFactorSecret(userID, secret string) error {
+	_, err := s.db.Exec(s.q(`INSERT INTO mooshroombase.two_factor (UserID, Secret, Enabled, CreatedAt) VALUES (?, ?, false, ?)`+s.onConflict("UserID", "Secret", "Enabled", "CreatedAt")), userID, secret, time.Now())
+	return err
+}
+
+func (s *SQLStore) EnableTwoFactor(userID string, recoveryCodeHashes []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(s.q(`UPDATE mooshroombase.two_factor SET Enabled = true WHERE UserID = ?`), userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(s.q(`DELETE FROM mooshroombase.recovery_codes WHERE UserID = ?`), userID); err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.Exec(s.q(`INSERT INTO mooshroombase.recovery_codes (CodeHash, UserID) VALUES (?, ?)`), hash, userID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// removes the recovery code, it tells if the code was one of the user's
+func (s *SQLStore) UseRecoveryCode(userID, codeHash string) (bool, error) {
+	result, err := s.db.Exec(s.q(`DELETE FROM mooshroombase.recovery_codes WHERE UserID = ? AND CodeHash = ?`), userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted == 1, err
+}
+
+// recovery codes are removed by the foreign key
+func (s *SQLStore) DeleteTwoFactor(userID string) error {
+	_, err := s.db.Exec(s.q(`DELETE FROM mooshroombase.two_factor WHERE UserID = ?`), userID)
+	return err
+}
+
+func (s *SQLStore) FindPasskeys(userID string) ([]types.Passkey, error) {
+	rows, err := s.db.Query(s.q(`SELECT ID, UserID, Name, PublicKey, AttestationType, Transports, SignCount, AAGUID, BackupEligible, BackupState, CreatedAt, LastUsedAt FROM mooshroombase.passkeys WHERE UserID = ?`), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passkeys := []types.Passkey{}
+	for rows.Next() {
+		var passkey types.Passkey
+		var transports string
+		err := rows.Scan(&passkey.ID, &passkey.UserID, &passkey.Name, &passkey.PublicKey, &passkey.AttestationType, &transports, &passkey.SignCount, &passkey.AAGUID, &passkey.BackupEligible, &passkey.BackupState, &passkey.CreatedAt, &passkey.LastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+		passkey.Transports = []string{}
+		if transports != "" {
+			passkey.Transports = strings.Split(transports, ",")
+		}
+		passkeys = append(passkeys, passkey)
+	}
+	return passkeys, rows.Err()
+}
+
+func (s *SQLStore) CreatePasskey(passkey types.Passkey) error {
+	_, err := s.db.Exec(s.q(`
+    INSERT INTO mooshroombase.passkeys (
+        ID,
+        UserID,
+        Name,
+        PublicKey,
+        AttestationType,
+        Transports,
+        SignCount,
+        AAGUID,
+        BackupEligible,
+        BackupState
+    ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`),
+		passkey.ID,
+		passkey.UserID,
+		passkey.Name,
+		passkey.PublicKey,
+		passkey.AttestationType,
+		strings.Join(passkey.Transports, ","),
+		passkey.SignCount,
+		passkey.AAGUID,
+		passkey.BackupEligible,
+		passkey.BackupState,
+	)
+	return err
+}
+
+func (s *SQLStore) UpdatePasskeyUsage(userID, id string, signCount uint32, backupState bool) error {
+	_, err := s.db.Exec(s.q(`UPDATE mooshroombase.passkeys SET SignCount = ?, BackupState = ?, LastUsedAt = ? WHERE ID = ? AND UserID = ?`), signCount, backupState, time.Now(), id, userID)
+	return err
+}
+
+func (s *SQLStore) DeletePasskey(userID, id string) error {
+	result, err := s.db.Exec(s.q(`DELETE FROM mooshroombase.passkeys WHERE ID = ? AND UserID = ?`), id, userID)
+	if err != nil {
+		return err
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLStore) CreatePasswordReset(reset types.PasswordReset) error {
+	_, err := s.db.Exec(s.q(`INSERT INTO mooshroombase.password_resets (TokenHash, UserID, ExpiresAt, CreatedAt) VALUES (?, ?, ?, ?)`), reset.TokenHash, reset.UserID, reset.ExpiresAt, reset.CreatedAt)
+	return err
+}
+
+// only the request which deletes the token is allowed to use it
+func (s *SQLStore) TakePasswordReset(tokenHash string) (types.PasswordReset, error) {
+	var reset types.PasswordReset
+	err := s.db.QueryRow(s.q(`SELECT TokenHash, UserID, ExpiresAt, CreatedAt FROM mooshroombase.password_resets WHERE TokenHash = ?`), tokenHash).Scan(&reset.TokenHash, &reset.UserID, &reset.ExpiresAt, &reset.CreatedAt)
+	if err != nil {
+		return reset, sqlError(err)
+	}
+
+	result, err := s.db.Exec(s.q(`DELETE FROM mooshroombase.password_resets WHERE TokenHash = ?`), tokenHash)
+	if err != nil {
+		return reset, err
+	}
+	if deleted, _ := result.RowsAffected(); deleted != 1 {
+		return types.PasswordReset{}, ErrNotFound
+	}
+	return reset, nil
+}
+
+func (s *SQLStore) DeletePasswordResets(userID string) error {
+	_, err := s.db.Exec(s.q(`DELETE FROM mooshroombase.password_resets WHERE UserID = ?`), userID)
+	return err
+}
+
+func scanSQLAPIKey(row scanner) (types.APIKey, error) {
+	var apiKey types.APIKey
+	var scopes string
+	var userID sql.NullString
+	var expiresAt, lastUsedAt sql.NullTime
+	err := row.Scan(&apiKey.ID, &apiKey.Name, &apiKey.KeyHash, &apiKey.Prefix, &scopes, &userID, &apiKey.CreatedAt, &expiresAt, &lastUsedAt)
+	if err != nil {
+		return apiKey, err
+	}
+
+	apiKey.Scopes = strings.Split(scopes, ",")
+	apiKey.UserID = userID.String
+	if expiresAt.Valid {
+		apiKey.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		apiKey.LastUsedAt = &lastUsedAt.Time
+	}
+	return apiKey, nil
+}
+
+// keys without a user are stored with a null user so the foreign key doesnt apply to them
+func (s *SQLStore) CreateAPIKey(apiKey types.APIKey) error {
+	userID := sql.NullString{String: apiKey.UserID, Valid: apiKey.UserID != ""}
+	_, err := s.db.Exec(s.q(`INSERT INTO mooshroombase.api_keys (ID, Name, KeyHash, Prefix, Scopes, UserID, CreatedAt, ExpiresAt) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+		apiKey.ID, apiKey.Name, apiKey.KeyHash, apiKey.Prefix, strings.Join(apiKey.Scopes, ","), userID, apiKey.CreatedAt, apiKey.ExpiresAt)
+	return err
+}
+
+func (s *SQLStore) ListAPIKeys() ([]types.APIKey, error) {
+	rows, err := s.db.Query(s.q(`SELECT ` + sqlAPIKeyColumns + ` FROM mooshroombase.api_keys ORDER BY CreatedAt DESC`))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []types.APIKey{}
+	for rows.Next() {
+		apiKey, err := scanSQLAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, apiKey)
+	}
+	return keys, rows.Err()
+}
+
+func (s *SQLStore) DeleteAPIKey(id string) error {
+	result, err := s.db.Exec(s.q(`DELETE FROM mooshroombase.api_keys WHERE ID = ?`), id)
+	if err != nil {
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLStore) FindAPIKeyByHash(keyHash string) (types.APIKey, error) {
+	apiKey, err := scanSQLAPIKey(s.db.QueryRow(s.q(`SELECT `+sqlAPIKeyColumns+` FROM mooshroombase.api_keys WHERE KeyHash = ?`), keyHash))
+	return apiKey, sqlError(err)
+}
+
+func (s *SQLStore) MarkAPIKeyUsed(id string, usedAt time.Time) error {
+	_, err := s.db.Exec(s.q(`UPDATE mooshroombase.api_keys SET LastUsedAt = ? WHERE ID = ?`), usedAt, id)
+	return err
+}
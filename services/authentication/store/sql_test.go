@@ -0,0 +1,77 @@
+package store
+
+import "testing"
+
+func TestQ(t *testing.T) {
+	tests := []struct {
+		name  string
+		store *SQLStore
+		query string
+		want  string
+	}{
+		{
+			name:  "mariadb keeps the query",
+			store: NewMariaStore(nil),
+			query: `SELECT ID FROM mooshroombase.users WHERE Email = ? AND UserName = ?`,
+			want:  `SELECT ID FROM mooshroombase.users WHERE Email = ? AND UserName = ?`,
+		},
+		{
+			name:  "postgres numbers the placeholders",
+			store: NewPostgresStore(nil),
+			query: `SELECT ID FROM mooshroombase.users WHERE Email = ? AND UserName = ?`,
+			want:  `SELECT ID FROM mooshroombase.users WHERE Email = $1 AND UserName = $2`,
+		},
+		{
+			name:  "postgres numbers placeholders in every part of the query",
+			store: NewPostgresStore(nil),
+			query: `SELECT u.ID FROM mooshroombase.users u WHERE LOWER(u.Email) LIKE ? OR LOWER(u.UserName) LIKE ? ORDER BY u.CreatedAt DESC LIMIT ? OFFSET ?`,
+			want:  `SELECT u.ID FROM mooshroombase.users u WHERE LOWER(u.Email) LIKE $1 OR LOWER(u.UserName) LIKE $2 ORDER BY u.CreatedAt DESC LIMIT $3 OFFSET $4`,
+		},
+		{
+			name:  "postgres without placeholders",
+			store: NewPostgresStore(nil),
+			query: `SELECT COUNT(*) FROM mooshroombase.users`,
+			want:  `SELECT COUNT(*) FROM mooshroombase.users`,
+		},
+		{
+			name:  "postgres counts past nine",
+			store: NewPostgresStore(nil),
+			query: `VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			want:  `VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		},
+		{
+			name:  "sqlite drops the schema",
+			store: NewSQLiteStore(nil),
+			query: `SELECT u.ID FROM mooshroombase.users u JOIN mooshroombase.user_roles r ON r.UserID = u.ID WHERE u.ID = ?`,
+			want:  `SELECT u.ID FROM users u JOIN user_roles r ON r.UserID = u.ID WHERE u.ID = ?`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.store.q(test.query); got != test.want {
+				t.Errorf("q(%q) = %q, want %q", test.query, got, test.want)
+			}
+		})
+	}
+}
+
+func TestOnConflict(t *testing.T) {
+	tests := []struct {
+		name  string
+		store *SQLStore
+		want  string
+	}{
+		{"mariadb", NewMariaStore(nil), ` ON DUPLICATE KEY UPDATE Value = VALUES(Value), UpdatedAt = VALUES(UpdatedAt)`},
+		{"postgres", NewPostgresStore(nil), ` ON CONFLICT (UserID, Name) DO UPDATE SET Value = EXCLUDED.Value, UpdatedAt = EXCLUDED.UpdatedAt`},
+		{"sqlite", NewSQLiteStore(nil), ` ON CONFLICT (UserID, Name) DO UPDATE SET Value = EXCLUDED.Value, UpdatedAt = EXCLUDED.UpdatedAt`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.store.onConflict("UserID, Name", "Value", "UpdatedAt"); got != test.want {
+				t.Errorf("onConflict() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
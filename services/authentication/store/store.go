@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/types"
+)
+
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrNotSupported = errors.New("not supported by the primary database")
+)
+
+// fields of a user to change, nil fields are left as they are
+type UserUpdate struct {
+	UserName          *string
+	FirstName         *string
+	LastName          *string
+	Email             *string
+	Password          *string
+	ProfilePicture    *string
+	Verified          *bool
+	VerificationToken *string
+	LastLoggedIn      *time.Time
+	Disabled          *bool
+	DisabledReason    string // only stored when Disabled is set
+	RawData           []types.RawUserData
+	ProfileFields     map[string]any // merged into the stored fields, nil values remove a field
+	Roles             []string
+}
+
+// everything the auth handlers need from the primary database, every backend gets one implementation
+// lookups of missing rows return ErrNotFound and features a backend doesnt have return ErrNotSupported
+type UserStore interface {
+	// users
+	CreateUser(user types.AuthUser) error
+	FindUserByID(id string) (types.AuthUser, error)
+	FindUserByEmail(email string) (types.AuthUser, error)
+	FindUserByUserName(username string) (types.AuthUser, error)
+	FindUserByOAuthAccount(provider, providerUserID string) (types.AuthUser, error)
+	UpdateUser(id string, update UserUpdate) error
+	DeleteUser(id string) error
+	ListUsers(search string, page, limit int) ([]types.AuthUser, int64, error)
+	FindRoles(userID string) ([]string, error)
+	IsDisabled(userID string) (bool, error)
+	AppendRawData(userID string, data types.RawUserData) error
+	WatchUser(ctx context.Context, userID string, send func(user types.AuthUser) error) error
+
+	// guests
+	UpgradeGuest(userID string, update UserUpdate) error
+	ExpiredGuests(createdBefore time.Time) ([]string, error)
+	DeleteGuest(userID string) (bool, error)
+
+	// oauth accounts
+	LinkOAuthAccount(userID string, account types.OAuthAccount) error
+	UnlinkOAuthAccount(userID, provider string) error
+
+	// two factor
+	FindTwoFactor(userID string) (types.TwoFactor, error)
+	SaveTwoFactorSecret(userID, secret string) error
+	EnableTwoFactor(userID string, recoveryCodeHashes []string) error
+	UseRecoveryCode(userID, codeHash string) (bool, error)
+	DeleteTwoFactor(userID string) error
+
+	// passkeys
+	FindPasskeys(userID string) ([]types.Passkey, error)
+	CreatePasskey(passkey types.Passkey) error
+	UpdatePasskeyUsage(userID, id string, signCount uint32, backupState bool) error
+	DeletePasskey(userID, id string) error
+
+	// password resets
+	CreatePasswordReset(reset types.PasswordReset) error
+	TakePasswordReset(tokenHash string) (types.PasswordReset, error)
+	DeletePasswordResets(userID string) error
+
+	// api keys
+	CreateAPIKey(apiKey types.APIKey) error
+	ListAPIKeys() ([]types.APIKey, error)
+	DeleteAPIKey(id string) error
+	FindAPIKeyByHash(keyHash string) (types.APIKey, error)
+	MarkAPIKeyUsed(id string, usedAt time.Time) error
+}
@@ -0,0 +1,78 @@
+package twofactor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/png"
+	"strings"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	"github.com/froggy-12/mooshroombase_v2/utils"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const recoveryCodesCount = 10
+
+// creates a new totp secret for the account
+func NewKey(accountName string) (*otp.Key, error) {
+	issuer := configs.Configs.Authentication.TOTPIssuer
+	if issuer == "" {
+		issuer = "Mooshroombase"
+	}
+
+	return totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: accountName,
+	})
+}
+
+// everything an authenticator app needs, the qr code is a png data url of the provisioning uri
+func EnrollmentData(key *otp.Key) (map[string]any, error) {
+	image, err := key.Image(256, 256)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image); err != nil {
+		return nil, err
+	}
+
+	return map[string]any{
+		"secret": key.Secret(),
+		"uri":    key.URL(),
+		"qrCode": "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// validates a totp code, a code is only accepted once even if it is still inside its time window
+func CheckCode(userID, secret, code string) (bool, error) {
+	if !totp.Validate(strings.TrimSpace(code), secret) {
+		return false, nil
+	}
+	return sessions.MarkTOTPCodeUsed(userID, strings.TrimSpace(code))
+}
+
+// generates the one time recovery codes, only their hashes are stored
+func GenerateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		token, err := utils.GenerateRandomToken(5)
+		if err != nil {
+			return nil, nil, err
+		}
+		code := token[:5] + "-" + token[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// recovery codes are accepted without the dash and in any case
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return utils.HashToken(strings.ReplaceAll(code, "-", ""))
+}
@@ -0,0 +1,107 @@
+package twofactor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
+	"github.com/pquerna/otp/totp"
+	"github.com/redis/go-redis/v9"
+)
+
+func setup(t *testing.T) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	sessions.Store = sessions.NewRedisStorage(client)
+}
+
+func TestCheckCode(t *testing.T) {
+	setup(t)
+	key, err := NewKey("user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := totp.GenerateCode(key.Secret(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	// the steps run in order against the same redis
+	tests := []struct {
+		name   string
+		userID string
+		code   string
+		want   bool
+	}{
+		{"wrong code", "user", wrong, false},
+		{"valid code with spaces", "user", " " + code + " ", true},
+		{"replayed code", "user", code, false},
+		{"same code for another user", "other", code, true},
+		{"not a number", "user", "abcdef", false},
+		{"empty code", "user", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := CheckCode(test.userID, key.Secret(), test.code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("CheckCode(%q) = %v, want %v", test.code, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodesCount || len(hashes) != recoveryCodesCount {
+		t.Fatalf("got %d codes and %d hashes, want %d", len(codes), len(hashes), recoveryCodesCount)
+	}
+
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if seen[code] {
+			t.Errorf("code %q was generated twice", code)
+		}
+		seen[code] = true
+		if hashes[i] == code || hashes[i] != HashRecoveryCode(code) {
+			t.Errorf("hash of %q doesnt match", code)
+		}
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	want := HashRecoveryCode("abcde-12345")
+
+	tests := []struct {
+		name string
+		code string
+		same bool
+	}{
+		{"same code", "abcde-12345", true},
+		{"without the dash", "abcde12345", true},
+		{"upper case", "ABCDE-12345", true},
+		{"with spaces", "  abcde-12345\n", true},
+		{"other code", "abcde-12346", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := HashRecoveryCode(test.code) == want; got != test.same {
+				t.Errorf("HashRecoveryCode(%q) matching = %v, want %v", test.code, got, test.same)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/types"
+)
+
+var (
+	ErrAccountLocked   = errors.New("account is temporarily locked because of too many failed log in attempts")
+	ErrTooManyAttempts = errors.New("too many failed log in attempts please try again later")
+)
+
+const (
+	maxLogInBackoff      = 5 * time.Minute
+	defaultMaxLogInTries = 5
+)
+
+func failedAccountKey(userID string) string {
+	return "mooshroombase:sessions:failed-logins:account:" + userID
+}
+
+func failedIPKey(ip string) string {
+	return "mooshroombase:sessions:failed-logins:ip:" + ip
+}
+
+func lockedAccountKey(userID string) string {
+	return "mooshroombase:sessions:locked:" + userID
+}
+
+func maxFailedLogIns() int {
+	if configs.Configs.Authentication.MaxFailedLogInAttempts <= 0 {
+		return defaultMaxLogInTries
+	}
+	return configs.Configs.Authentication.MaxFailedLogInAttempts
+}
+
+// failed attempts are forgotten after the lock duration too
+func accountLockDuration() time.Duration {
+	if configs.Configs.Authentication.AccountLockDuration <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Minute * time.Duration(configs.Configs.Authentication.AccountLockDuration)
+}
+
+// every failed attempt doubles the time until the next one is allowed, starting with one second
+func logInBackoff(failures int64) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+	if failures > 20 {
+		return maxLogInBackoff
+	}
+	return min(time.Second<<(failures-1), maxLogInBackoff)
+}
+
+// the lock stores the time it ends at
+func lockedUntil(ctx context.Context, userID string) (time.Time, bool, error) {
+	value, found, err := Store.Get(ctx, lockedAccountKey(userID))
+	if err != nil || !found {
+		return time.Time{}, false, err
+	}
+	until, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.Unix(until, 0), true, nil
+}
+
+// time left until the counter at key allows the next attempt
+func backoffRemaining(ctx context.Context, key string) (time.Duration, error) {
+	values, err := Store.HGetAll(ctx, key)
+	if err != nil || len(values) == 0 {
+		return 0, err
+	}
+	count, _ := strconv.ParseInt(values["count"], 10, 64)
+	last, _ := strconv.ParseInt(values["last"], 10, 64)
+	return time.Until(time.Unix(last, 0).Add(logInBackoff(count))), nil
+}
+
+// checks if a log in attempt for the account from the ip is allowed right now, userID is empty for unknown emails
+func CheckLogIn(userID, ip string) (time.Duration, error) {
+	ctx := context.Background()
+
+	if userID != "" {
+		until, locked, err := lockedUntil(ctx, userID)
+		if err != nil {
+			return 0, err
+		}
+		if locked {
+			return time.Until(until), ErrAccountLocked
+		}
+
+		wait, err := backoffRemaining(ctx, failedAccountKey(userID))
+		if err != nil {
+			return 0, err
+		}
+		if wait > 0 {
+			return wait, ErrTooManyAttempts
+		}
+	}
+
+	wait, err := backoffRemaining(ctx, failedIPKey(ip))
+	if err != nil {
+		return 0, err
+	}
+	if wait > 0 {
+		return wait, ErrTooManyAttempts
+	}
+	return 0, nil
+}
+
+func recordFailure(ctx context.Context, key string) (int64, error) {
+	count, err := Store.HIncrBy(ctx, key, "count", 1)
+	if err != nil {
+		return 0, err
+	}
+	if err := Store.HSet(ctx, key, map[string]any{"last": time.Now().Unix()}); err != nil {
+		return 0, err
+	}
+	return count, Store.Expire(ctx, key, accountLockDuration())
+}
+
+// counts a failed attempt, once the account reaches the limit it gets locked and the returned time is set
+func RecordFailedLogIn(userID, ip string) (time.Time, error) {
+	ctx := context.Background()
+
+	if _, err := recordFailure(ctx, failedIPKey(ip)); err != nil {
+		return time.Time{}, err
+	}
+	if userID == "" {
+		return time.Time{}, nil
+	}
+
+	count, err := recordFailure(ctx, failedAccountKey(userID))
+	if err != nil {
+		return time.Time{}, err
+	}
+	if count < int64(maxFailedLogIns()) {
+		return time.Time{}, nil
+	}
+
+	until := time.Now().Add(accountLockDuration())
+	if err := Store.Set(ctx, lockedAccountKey(userID), strconv.FormatInt(until.Unix(), 10), accountLockDuration()); err != nil {
+		return time.Time{}, err
+	}
+	_, err = Store.Del(ctx, failedAccountKey(userID))
+	return until, err
+}
+
+// the ip counter is kept so a working password for one account doesnt reset the throttling of the ip
+func ResetFailedLogIns(userID string) error {
+	_, err := Store.Del(context.Background(), failedAccountKey(userID))
+	return err
+}
+
+func UnlockAccount(userID string) error {
+	_, err := Store.Del(context.Background(), lockedAccountKey(userID), failedAccountKey(userID))
+	return err
+}
+
+func LockStatusOf(userID string) (types.LockStatus, error) {
+	ctx := context.Background()
+	status := types.LockStatus{}
+
+	until, locked, err := lockedUntil(ctx, userID)
+	if err != nil {
+		return status, err
+	}
+	status.Locked = locked
+	if locked {
+		status.LockedUntil = until
+	}
+
+	count, found, err := Store.HGet(ctx, failedAccountKey(userID), "count")
+	if err != nil || !found {
+		return status, err
+	}
+	status.FailedAttempts, err = strconv.Atoi(count)
+	return status, err
+}
@@ -0,0 +1,147 @@
+package sessions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+)
+
+func useLockout(t *testing.T, maxAttempts int) {
+	t.Helper()
+	previous := configs.Configs.Authentication
+	configs.Configs.Authentication.MaxFailedLogInAttempts = maxAttempts
+	configs.Configs.Authentication.AccountLockDuration = 15
+	t.Cleanup(func() { configs.Configs.Authentication = previous })
+}
+
+func mustFail(t *testing.T, userID, ip string) time.Time {
+	t.Helper()
+	until, err := RecordFailedLogIn(userID, ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return until
+}
+
+func TestLogInBackoff(t *testing.T) {
+	tests := []struct {
+		failures int64
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{5, 16 * time.Second},
+		{9, 256 * time.Second},
+		{10, maxLogInBackoff},
+		{21, maxLogInBackoff},
+		{64, maxLogInBackoff},
+	}
+
+	for _, test := range tests {
+		if got := logInBackoff(test.failures); got != test.want {
+			t.Errorf("logInBackoff(%d) = %v, want %v", test.failures, got, test.want)
+		}
+	}
+}
+
+func TestCheckLogIn(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T)
+		userID  string
+		ip      string
+		wantErr error
+	}{
+		{"no failures", func(t *testing.T) {}, "user", "1.1.1.1", nil},
+		{"failure of the account", func(t *testing.T) { mustFail(t, "user", "2.2.2.2") }, "user", "1.1.1.1", ErrTooManyAttempts},
+		{"failure from the ip", func(t *testing.T) { mustFail(t, "other", "1.1.1.1") }, "user", "1.1.1.1", ErrTooManyAttempts},
+		{"unknown email from the ip", func(t *testing.T) { mustFail(t, "", "1.1.1.1") }, "", "1.1.1.1", ErrTooManyAttempts},
+		{"failure of another account from another ip", func(t *testing.T) { mustFail(t, "other", "2.2.2.2") }, "user", "1.1.1.1", nil},
+		{
+			name: "locked account",
+			prepare: func(t *testing.T) {
+				for range 3 {
+					mustFail(t, "user", "2.2.2.2")
+				}
+			},
+			userID:  "user",
+			ip:      "1.1.1.1",
+			wantErr: ErrAccountLocked,
+		},
+		{
+			name: "unlocked account",
+			prepare: func(t *testing.T) {
+				for range 3 {
+					mustFail(t, "user", "2.2.2.2")
+				}
+				if err := UnlockAccount("user"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			userID: "user",
+			ip:     "1.1.1.1",
+		},
+		{
+			// a working password resets the account but the ip stays throttled
+			name: "reset account",
+			prepare: func(t *testing.T) {
+				mustFail(t, "user", "1.1.1.1")
+				if err := ResetFailedLogIns("user"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			userID:  "user",
+			ip:      "1.1.1.1",
+			wantErr: ErrTooManyAttempts,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setup(t)
+			useLockout(t, 3)
+			test.prepare(t)
+
+			wait, err := CheckLogIn(test.userID, test.ip)
+			if err != test.wantErr {
+				t.Fatalf("CheckLogIn() error = %v, want %v", err, test.wantErr)
+			}
+			if (err == nil) != (wait <= 0) {
+				t.Errorf("CheckLogIn() wait = %v with error %v", wait, err)
+			}
+		})
+	}
+}
+
+func TestAccountLock(t *testing.T) {
+	setup(t)
+	useLockout(t, 3)
+
+	for attempt := 1; attempt < 3; attempt++ {
+		if until := mustFail(t, "user", "1.1.1.1"); !until.IsZero() {
+			t.Fatalf("attempt %d locked the account until %v", attempt, until)
+		}
+	}
+	status, err := LockStatusOf("user")
+	if err != nil || status.Locked || status.FailedAttempts != 2 {
+		t.Fatalf("LockStatusOf() = %+v, %v, want 2 failed attempts", status, err)
+	}
+
+	until := mustFail(t, "user", "1.1.1.1")
+	if wantAbout := time.Now().Add(15 * time.Minute); until.Before(wantAbout.Add(-time.Minute)) || until.After(wantAbout) {
+		t.Fatalf("the last attempt locked the account until %v, want about %v", until, wantAbout)
+	}
+	status, err = LockStatusOf("user")
+	if err != nil || !status.Locked || status.LockedUntil.Unix() != until.Unix() || status.FailedAttempts != 0 {
+		t.Errorf("LockStatusOf() = %+v, %v, want it locked until %v", status, err, until)
+	}
+
+	if err := UnlockAccount("user"); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := LockStatusOf("user"); err != nil || status.Locked {
+		t.Errorf("LockStatusOf() after unlocking = %+v, %v", status, err)
+	}
+}
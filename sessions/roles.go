@@ -0,0 +1,68 @@
+package sessions
+
+import (
+	"slices"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+)
+
+// role of anonymous users until they upgrade to a full account
+const GuestRole = "guest"
+
+// loads the roles stored for a user, set from the api depending on the primary database
+var UserRoles func(userID string) ([]string, error)
+
+// configs created before roles existed get the same defaults as new ones
+func DefaultRoles() []string {
+	if len(configs.Configs.Authentication.DefaultRoles) == 0 {
+		return []string{"user"}
+	}
+	return configs.Configs.Authentication.DefaultRoles
+}
+
+func RolePermissions() map[string][]string {
+	if configs.Configs.Authentication.RolePermissions == nil {
+		return map[string][]string{
+			"admin": {"*"},
+			"user":  {"files:upload", "email:send"},
+			"guest": {"files:upload"},
+		}
+	}
+	return configs.Configs.Authentication.RolePermissions
+}
+
+// roles which end up in the access token, users without stored roles get the default ones
+func RolesOf(userID string) ([]string, error) {
+	roles := []string{}
+	if UserRoles != nil {
+		stored, err := UserRoles(userID)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, stored...)
+	}
+	if len(roles) == 0 {
+		roles = append(roles, DefaultRoles()...)
+	}
+	if slices.Contains(configs.Configs.Authentication.AdminUserIDs, userID) && !slices.Contains(roles, "admin") {
+		roles = append(roles, "admin")
+	}
+	return roles, nil
+}
+
+func IsKnownRole(role string) bool {
+	_, ok := RolePermissions()[role]
+	return ok
+}
+
+func HasPermission(roles []string, permission string) bool {
+	permissions := RolePermissions()
+	for _, role := range roles {
+		for _, allowed := range permissions[role] {
+			if allowed == "*" || allowed == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package sessions
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+)
+
+func TestHasPermission(t *testing.T) {
+	previous := configs.Configs.Authentication
+	configs.Configs.Authentication.RolePermissions = nil
+	t.Cleanup(func() { configs.Configs.Authentication = previous })
+
+	tests := []struct {
+		name       string
+		roles      []string
+		permission string
+		want       bool
+	}{
+		{"admin can do everything", []string{"admin"}, "users:delete", true},
+		{"user permission", []string{"user"}, "files:upload", true},
+		{"missing permission", []string{"user"}, "users:delete", false},
+		{"any of the roles", []string{"user", "admin"}, "users:delete", true},
+		{"unknown role", []string{"owner"}, "files:upload", false},
+		{"no roles", nil, "files:upload", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := HasPermission(test.roles, test.permission); got != test.want {
+				t.Errorf("HasPermission(%v, %q) = %v, want %v", test.roles, test.permission, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRolesOf(t *testing.T) {
+	previous, previousUserRoles := configs.Configs.Authentication, UserRoles
+	configs.Configs.Authentication.DefaultRoles = []string{"member"}
+	configs.Configs.Authentication.AdminUserIDs = []string{"owner"}
+	t.Cleanup(func() { configs.Configs.Authentication, UserRoles = previous, previousUserRoles })
+
+	stored := map[string][]string{"editor": {"editor"}, "owner": {"user"}}
+	UserRoles = func(userID string) ([]string, error) {
+		if userID == "broken" {
+			return nil, errors.New("database is down")
+		}
+		return stored[userID], nil
+	}
+
+	tests := []struct {
+		name    string
+		userID  string
+		want    []string
+		wantErr bool
+	}{
+		{"stored roles", "editor", []string{"editor"}, false},
+		{"default roles", "new", []string{"member"}, false},
+		{"configured admin", "owner", []string{"user", "admin"}, false},
+		{"store error", "broken", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := RolesOf(test.userID)
+			if (err != nil) != test.wantErr || !slices.Equal(got, test.want) {
+				t.Errorf("RolesOf(%q) = %v, %v, want %v", test.userID, got, err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,585 @@
+package sessions
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/jwtkeys"
+	"github.com/froggy-12/mooshroombase_v2/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	AccessTokenCookieName  = "jwtToken"
+	RefreshTokenCookieName = "refreshToken"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used, the session has been revoked")
+	ErrSessionRevoked      = errors.New("session has been revoked please log in again")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrInvalidMFAToken     = errors.New("invalid or expired two factor token please log in again")
+	ErrUserDisabled        = errors.New("user has been disabled by an admin")
+	ErrLegacyTokenUsed     = errors.New("token has already been exchanged for a session please log in again")
+)
+
+// tells if an admin has disabled the user, set from the api depending on the primary database
+var UserDisabled func(userID string) (bool, error)
+
+func IsUserDisabled(userID string) (bool, error) {
+	if UserDisabled == nil {
+		return false, nil
+	}
+	return UserDisabled(userID)
+}
+
+// every log in creates a session, its refresh tokens form one family so reusing an old one revokes all of them
+type TokenPair struct {
+	SessionID    string `json:"sessionId"`
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// counts how often all sessions of the user were revoked, it has no expiry so the count never starts over
+func userGenerationKey(userID string) string {
+	return "mooshroombase:sessions:generation:" + userID
+}
+
+func revokedTokenKey(jti string) string {
+	return "mooshroombase:sessions:revoked-token:" + jti
+}
+
+func sessionKey(sessionID string) string {
+	return "mooshroombase:sessions:session:" + sessionID
+}
+
+// ids of the sessions a user has, expired ones are cleaned up while listing
+func userSessionsKey(userID string) string {
+	return "mooshroombase:sessions:user:" + userID
+}
+
+func mfaChallengeKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "mooshroombase:sessions:mfa:" + hex.EncodeToString(sum[:])
+}
+
+// marks a token from before sessions existed as exchanged, it lives until legacy tokens are no longer accepted
+func legacyTokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "mooshroombase:sessions:legacy:" + hex.EncodeToString(sum[:])
+}
+
+func refreshTokenKey(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return "mooshroombase:sessions:refresh:" + hex.EncodeToString(sum[:])
+}
+
+func AccessTokenTTL() time.Duration {
+	if configs.Configs.HttpConfigurations.AccessTokenExpirationTime <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Minute * time.Duration(configs.Configs.HttpConfigurations.AccessTokenExpirationTime)
+}
+
+// sessions and refresh tokens live as long as the jwt expiration time, nothing issued by them can outlive it
+func RefreshTokenTTL() time.Duration {
+	return time.Hour * 24 * time.Duration(configs.Configs.HttpConfigurations.JWTTokenExpirationTime)
+}
+
+// roles are looked up again for every access token so role changes apply after the next refresh
+func generateAccessToken(userID, sessionID string) (string, error) {
+	roles, err := RolesOf(userID)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub":   userID,
+		"jti":   uuid.New().String(),
+		"sid":   sessionID,
+		"roles": roles,
+		"exp":   now.Add(AccessTokenTTL()).Unix(),
+		"nbf":   now.Unix(),
+		"iat":   now.Unix(),
+	}
+	if configs.Configs.HttpConfigurations.JWTIssuer != "" {
+		claims["iss"] = configs.Configs.HttpConfigurations.JWTIssuer
+	}
+	if configs.Configs.HttpConfigurations.JWTAudience != "" {
+		claims["aud"] = configs.Configs.HttpConfigurations.JWTAudience
+	}
+
+	return jwtkeys.Sign(claims)
+}
+
+func generateRefreshToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// stores a new refresh token of the session and signs an access token next to it
+func issueTokenPair(ctx context.Context, userID, sessionID string) (TokenPair, error) {
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	err = Store.HSet(ctx, refreshTokenKey(refreshToken), map[string]any{"sessionId": sessionID, "userId": userID, "used": 0})
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if err := Store.Expire(ctx, refreshTokenKey(refreshToken), RefreshTokenTTL()); err != nil {
+		return TokenPair{}, err
+	}
+
+	accessToken, err := generateAccessToken(userID, sessionID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{SessionID: sessionID, AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// creates a new session for the user and issues its first token pair
+func CreateSession(userID, userAgent, ip string) (TokenPair, error) {
+	disabled, err := IsUserDisabled(userID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if disabled {
+		return TokenPair{}, ErrUserDisabled
+	}
+
+	ctx := context.Background()
+	sessionID := uuid.New().String()
+	now := time.Now().Unix()
+
+	// read before the session is stored so a revocation running at the same time always covers it
+	generation, err := userGeneration(ctx, userID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	err = Store.HSet(ctx, sessionKey(sessionID), map[string]any{
+		"userId":     userID,
+		"userAgent":  userAgent,
+		"ip":         ip,
+		"createdAt":  now,
+		"lastSeenAt": now,
+		"generation": generation,
+		"revoked":    0,
+	})
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if err := Store.Expire(ctx, sessionKey(sessionID), RefreshTokenTTL()); err != nil {
+		return TokenPair{}, err
+	}
+
+	if err := Store.SAdd(ctx, userSessionsKey(userID), sessionID); err != nil {
+		return TokenPair{}, err
+	}
+	if err := Store.Expire(ctx, userSessionsKey(userID), RefreshTokenTTL()); err != nil {
+		return TokenPair{}, err
+	}
+
+	return issueTokenPair(ctx, userID, sessionID)
+}
+
+// exchanges a refresh token for a new pair, every refresh token can only be used once
+func Rotate(refreshToken string) (TokenPair, error) {
+	ctx := context.Background()
+	key := refreshTokenKey(refreshToken)
+
+	values, err := Store.HGetAll(ctx, key)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if len(values) == 0 {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+	sessionID, userID := values["sessionId"], values["userId"]
+
+	// incrementing is atomic so only one request can ever see the token unused
+	used, err := Store.HIncrBy(ctx, key, "used", 1)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if used > 1 {
+		if err := RevokeSession(sessionID); err != nil {
+			return TokenPair{}, err
+		}
+		return TokenPair{}, ErrRefreshTokenReused
+	}
+
+	revoked, err := IsSessionRevoked(sessionID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if revoked {
+		return TokenPair{}, ErrSessionRevoked
+	}
+
+	if err := Store.Expire(ctx, sessionKey(sessionID), RefreshTokenTTL()); err != nil {
+		return TokenPair{}, err
+	}
+	if err := Store.Expire(ctx, userSessionsKey(userID), RefreshTokenTTL()); err != nil {
+		return TokenPair{}, err
+	}
+
+	return issueTokenPair(ctx, userID, sessionID)
+}
+
+// id of the session a refresh token belongs to
+func SessionIDFromRefreshToken(refreshToken string) (string, error) {
+	sessionID, found, err := Store.HGet(context.Background(), refreshTokenKey(refreshToken), "sessionId")
+	if err == nil && !found {
+		return "", ErrInvalidRefreshToken
+	}
+	return sessionID, err
+}
+
+// revokes the session with all of its refresh and access tokens
+func RevokeSession(sessionID string) error {
+	ctx := context.Background()
+	userID, found, err := Store.HGet(ctx, sessionKey(sessionID), "userId")
+	if err != nil || !found {
+		return err
+	}
+	if err := Store.HSet(ctx, sessionKey(sessionID), map[string]any{"revoked": 1}); err != nil {
+		return err
+	}
+	return Store.SRem(ctx, userSessionsKey(userID), sessionID)
+}
+
+// revokes a session only if it belongs to the user so users cant revoke sessions of others
+func RevokeUserSession(userID, sessionID string) error {
+	owner, found, err := Store.HGet(context.Background(), sessionKey(sessionID), "userId")
+	if err != nil {
+		return err
+	}
+	if !found || owner != userID {
+		return ErrSessionNotFound
+	}
+	return RevokeSession(sessionID)
+}
+
+// a session is revoked when it was revoked itself, expired or all sessions of its user were revoked after it was created
+func IsSessionRevoked(sessionID string) (bool, error) {
+	values, err := Store.HGetAll(context.Background(), sessionKey(sessionID))
+	if err != nil {
+		return false, err
+	}
+	return isSessionRevoked(values)
+}
+
+func isSessionRevoked(values map[string]string) (bool, error) {
+	if len(values) == 0 || values["revoked"] == "1" {
+		return true, nil
+	}
+
+	generation, err := strconv.ParseInt(values["generation"], 10, 64)
+	if err != nil {
+		return false, err
+	}
+	current, err := userGeneration(context.Background(), values["userId"])
+	if err != nil {
+		return false, err
+	}
+	return generation < current, nil
+}
+
+// id of the user of the session, revoked and expired sessions return ErrSessionRevoked
+func SessionUserID(sessionID string) (string, error) {
+	values, err := Store.HGetAll(context.Background(), sessionKey(sessionID))
+	if err != nil {
+		return "", err
+	}
+	revoked, err := isSessionRevoked(values)
+	if err != nil {
+		return "", err
+	}
+	if revoked {
+		return "", ErrSessionRevoked
+	}
+	return values["userId"], nil
+}
+
+// updates when and from where the session was used last, a session which expired since it was checked
+// returns ErrSessionRevoked instead of being stored again without its expiry
+func Touch(sessionID, ip string) error {
+	touched, err := Store.HSetIfExists(context.Background(), sessionKey(sessionID), map[string]any{"lastSeenAt": time.Now().Unix(), "ip": ip})
+	if err != nil {
+		return err
+	}
+	if !touched {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// lists the active sessions of the user, currentSessionID is marked as the current one
+func ListSessions(userID, currentSessionID string) ([]types.Session, error) {
+	ctx := context.Background()
+	sessionIDs, err := Store.SMembers(ctx, userSessionsKey(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	list := []types.Session{}
+	for _, sessionID := range sessionIDs {
+		values, err := Store.HGetAll(ctx, sessionKey(sessionID))
+		if err != nil {
+			return nil, err
+		}
+		revoked, err := isSessionRevoked(values)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			Store.SRem(ctx, userSessionsKey(userID), sessionID)
+			continue
+		}
+
+		createdAt, _ := strconv.ParseInt(values["createdAt"], 10, 64)
+		lastSeenAt, _ := strconv.ParseInt(values["lastSeenAt"], 10, 64)
+		list = append(list, types.Session{
+			ID:         sessionID,
+			UserAgent:  values["userAgent"],
+			IP:         values["ip"],
+			CreatedAt:  time.Unix(createdAt, 0),
+			LastSeenAt: time.Unix(lastSeenAt, 0),
+			Current:    sessionID == currentSessionID,
+		})
+	}
+
+	return list, nil
+}
+
+// puts the jti of an access token on the denylist
+func RevokeToken(jti string) error {
+	return Store.Set(context.Background(), revokedTokenKey(jti), "1", AccessTokenTTL())
+}
+
+func IsTokenRevoked(jti string) (bool, error) {
+	if jti == "" {
+		return false, nil
+	}
+	return Store.Exists(context.Background(), revokedTokenKey(jti))
+}
+
+// revokes every session and token issued to the user until now, sessions created after it by the same request stay valid
+// every session remembers the generation it was created in so this doesnt depend on the clock
+func RevokeAllForUser(userID string) error {
+	_, err := Store.Incr(context.Background(), userGenerationKey(userID))
+	return err
+}
+
+func userGeneration(ctx context.Context, userID string) (int64, error) {
+	value, found, err := Store.Get(ctx, userGenerationKey(userID))
+	if err != nil || !found {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func SetTokenCookies(c *fiber.Ctx, pair TokenPair) {
+	c.Cookie(&fiber.Cookie{
+		Name:     AccessTokenCookieName,
+		Value:    pair.AccessToken,
+		Path:     "/",
+		HTTPOnly: true,
+		Secure:   true,
+		MaxAge:   int(RefreshTokenTTL().Seconds()),
+	})
+	// the refresh token is only needed by the refresh and log out routes
+	c.Cookie(&fiber.Cookie{
+		Name:     RefreshTokenCookieName,
+		Value:    pair.RefreshToken,
+		Path:     "/api/auth",
+		HTTPOnly: true,
+		Secure:   true,
+		MaxAge:   int(RefreshTokenTTL().Seconds()),
+	})
+}
+
+func ClearTokenCookies(c *fiber.Ctx) {
+	for name, path := range map[string]string{AccessTokenCookieName: "/", RefreshTokenCookieName: "/api/auth"} {
+		c.Cookie(&fiber.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     path,
+			HTTPOnly: true,
+			Secure:   true,
+			MaxAge:   -1,
+			Expires:  time.Unix(0, 0),
+		})
+	}
+}
+
+// clients which cant use cookies ask for the tokens in the response body with ?returnTokens=true
+func TokensInBody(c *fiber.Ctx) bool {
+	return c.Query("returnTokens") == "true"
+}
+
+// creates a new session for the device of the request, its tokens are set as cookies unless the client asked for them in the body
+func LogIn(c *fiber.Ctx, userID string) (TokenPair, error) {
+	pair, err := CreateSession(userID, c.Get(fiber.HeaderUserAgent), c.IP())
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if !TokensInBody(c) {
+		SetTokenCookies(c, pair)
+	}
+	return pair, nil
+}
+
+// starts a session for a token signed before sessions existed, the caller has to check the token first
+// each token can be exchanged once and none of them after the user logged out everywhere
+func ExchangeLegacyToken(c *fiber.Ctx, token, userID string) (TokenPair, error) {
+	deadline, err := time.Parse(time.RFC3339, configs.Configs.HttpConfigurations.JWTLegacyTokensUntil)
+	if err != nil || time.Now().After(deadline) {
+		return TokenPair{}, ErrLegacyTokenUsed
+	}
+
+	ctx := context.Background()
+	generation, err := userGeneration(ctx, userID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if generation > 0 {
+		return TokenPair{}, ErrSessionRevoked
+	}
+
+	unused, err := Store.SetNX(ctx, legacyTokenKey(token), "1", time.Until(deadline))
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if !unused {
+		return TokenPair{}, ErrLegacyTokenUsed
+	}
+
+	return LogIn(c, userID)
+}
+
+// challenges are given out after the password check of users with two factor enabled
+const (
+	mfaChallengeTTL      = 5 * time.Minute
+	mfaChallengeAttempts = 5
+)
+
+// creates the short lived token which has to be sent back together with the totp code
+func CreateMFAChallenge(userID string) (string, error) {
+	token, err := generateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	ctx := context.Background()
+	if err := Store.HSet(ctx, mfaChallengeKey(token), map[string]any{"userId": userID, "attempts": 0}); err != nil {
+		return "", err
+	}
+	if err := Store.Expire(ctx, mfaChallengeKey(token), mfaChallengeTTL); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// returns the user of the challenge and counts the attempt, the challenge is dropped after too many attempts
+func MFAChallengeUser(token string) (string, error) {
+	ctx := context.Background()
+	userID, found, err := Store.HGet(ctx, mfaChallengeKey(token), "userId")
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", ErrInvalidMFAToken
+	}
+
+	attempts, err := Store.HIncrBy(ctx, mfaChallengeKey(token), "attempts", 1)
+	if err != nil {
+		return "", err
+	}
+	if attempts > mfaChallengeAttempts {
+		Store.Del(ctx, mfaChallengeKey(token))
+		return "", ErrInvalidMFAToken
+	}
+	return userID, nil
+}
+
+// challenges can only be completed once
+func DeleteMFAChallenge(token string) error {
+	_, err := Store.Del(context.Background(), mfaChallengeKey(token))
+	return err
+}
+
+// remembers a used totp code so it cant be replayed while it is still valid, returns false if it was used already
+func MarkTOTPCodeUsed(userID, code string) (bool, error) {
+	return Store.SetNX(context.Background(), "mooshroombase:sessions:totp-used:"+userID+":"+code, "1", 2*time.Minute)
+}
+
+// webauthn ceremonies keep their challenge here between the begin and finish requests
+const ceremonyTTL = 5 * time.Minute
+
+func StoreCeremony(data []byte) (string, error) {
+	id, err := generateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	if err := Store.Set(context.Background(), "mooshroombase:sessions:webauthn:"+id, string(data), ceremonyTTL); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// returns the stored ceremony and removes it so every challenge can only be answered once
+func TakeCeremony(id string) ([]byte, error) {
+	data, found, err := Store.GetDel(context.Background(), "mooshroombase:sessions:webauthn:"+id)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("passkey ceremony has expired please try again")
+	}
+	return []byte(data), nil
+}
+
+// oauth link flows remember the session which started them so the callback can check it again
+func StoreOAuthLink(state, sessionID string, ttl time.Duration) error {
+	return Store.Set(context.Background(), "mooshroombase:sessions:oauth-link:"+state, sessionID, ttl)
+}
+
+// returns the session which started the link flow, every state can only be used once
+func TakeOAuthLink(state string) (string, error) {
+	sessionID, found, err := Store.GetDel(context.Background(), "mooshroombase:sessions:oauth-link:"+state)
+	if err == nil && !found {
+		return "", ErrSessionNotFound
+	}
+	return sessionID, err
+}
+
+// one time tokens like magic links are only valid while their id is stored here
+func StoreOneTimeToken(kind, id string, ttl time.Duration) error {
+	return Store.Set(context.Background(), "mooshroombase:sessions:"+kind+":"+id, "1", ttl)
+}
+
+// returns true only for the first request which uses the token
+func ConsumeOneTimeToken(kind, id string) (bool, error) {
+	deleted, err := Store.Del(context.Background(), "mooshroombase:sessions:"+kind+":"+id)
+	if err != nil {
+		return false, err
+	}
+	return deleted == 1, nil
+}
@@ -0,0 +1,264 @@
+package sessions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/redis/go-redis/v9"
+)
+
+func setup(t *testing.T) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	Store = NewRedisStorage(client)
+
+	previous := configs.Configs.HttpConfigurations
+	configs.Configs.HttpConfigurations = configs.HttpConfigurations{JWTSecret: "secret", JWTTokenExpirationTime: 7, AccessTokenExpirationTime: 15}
+	t.Cleanup(func() { configs.Configs.HttpConfigurations = previous })
+}
+
+func mustCreateSession(t *testing.T, userID string) TokenPair {
+	t.Helper()
+	pair, err := CreateSession(userID, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pair
+}
+
+func TestRotate(t *testing.T) {
+	tests := []struct {
+		name string
+		// returns the refresh token to rotate and the ones which have to be rejected afterwards
+		prepare  func(t *testing.T) (string, []string)
+		wantErr  error
+		wantDead error
+	}{
+		{
+			name: "unused token",
+			prepare: func(t *testing.T) (string, []string) {
+				return mustCreateSession(t, "user").RefreshToken, nil
+			},
+		},
+		{
+			name: "unknown token",
+			prepare: func(t *testing.T) (string, []string) {
+				return "unknown", nil
+			},
+			wantErr: ErrInvalidRefreshToken,
+		},
+		{
+			// the stolen token was used first so every token of the session stops working
+			name: "reused token revokes the session",
+			prepare: func(t *testing.T) (string, []string) {
+				first := mustCreateSession(t, "user")
+				second, err := Rotate(first.RefreshToken)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return first.RefreshToken, []string{second.RefreshToken}
+			},
+			wantErr:  ErrRefreshTokenReused,
+			wantDead: ErrSessionRevoked,
+		},
+		{
+			name: "revoked session",
+			prepare: func(t *testing.T) (string, []string) {
+				pair := mustCreateSession(t, "user")
+				if err := RevokeSession(pair.SessionID); err != nil {
+					t.Fatal(err)
+				}
+				return pair.RefreshToken, nil
+			},
+			wantErr: ErrSessionRevoked,
+		},
+		{
+			name: "all sessions of the user revoked",
+			prepare: func(t *testing.T) (string, []string) {
+				pair := mustCreateSession(t, "user")
+				if err := RevokeAllForUser("user"); err != nil {
+					t.Fatal(err)
+				}
+				return pair.RefreshToken, nil
+			},
+			wantErr: ErrSessionRevoked,
+		},
+		{
+			// like a password change which revokes everything and logs the current device in again within the same second
+			name: "session created after revoking all sessions",
+			prepare: func(t *testing.T) (string, []string) {
+				if err := RevokeAllForUser("user"); err != nil {
+					t.Fatal(err)
+				}
+				return mustCreateSession(t, "user").RefreshToken, nil
+			},
+		},
+		{
+			name: "all sessions of another user revoked",
+			prepare: func(t *testing.T) (string, []string) {
+				pair := mustCreateSession(t, "user")
+				if err := RevokeAllForUser("other"); err != nil {
+					t.Fatal(err)
+				}
+				return pair.RefreshToken, nil
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setup(t)
+			refreshToken, dead := test.prepare(t)
+
+			pair, err := Rotate(refreshToken)
+			if err != test.wantErr {
+				t.Fatalf("Rotate() error = %v, want %v", err, test.wantErr)
+			}
+			if err == nil && (pair.RefreshToken == "" || pair.RefreshToken == refreshToken || pair.AccessToken == "") {
+				t.Errorf("Rotate() = %+v, want a new pair", pair)
+			}
+			for _, token := range dead {
+				if _, err := Rotate(token); err != test.wantDead {
+					t.Errorf("Rotate() of a later token error = %v, want %v", err, test.wantDead)
+				}
+			}
+		})
+	}
+}
+
+func TestRotatedTokenCanOnlyBeUsedOnce(t *testing.T) {
+	setup(t)
+	pair := mustCreateSession(t, "user")
+
+	next, err := Rotate(pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Rotate(next.RefreshToken); err != nil {
+		t.Fatalf("Rotate() of the new token error = %v", err)
+	}
+	if _, err := Rotate(next.RefreshToken); err != ErrRefreshTokenReused {
+		t.Fatalf("Rotate() of the used token error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+
+	revoked, err := IsSessionRevoked(pair.SessionID)
+	if err != nil || !revoked {
+		t.Errorf("IsSessionRevoked() = %v, %v, want the session revoked", revoked, err)
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	setup(t)
+	current := mustCreateSession(t, "user")
+	other := mustCreateSession(t, "user")
+	revoked := mustCreateSession(t, "user")
+	mustCreateSession(t, "someone else")
+	if err := RevokeSession(revoked.SessionID); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := ListSessions("user", current.SessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, session := range list {
+		got[session.ID] = session.Current
+		if session.UserAgent != "test" || session.IP != "127.0.0.1" {
+			t.Errorf("session %s was recorded as %q from %q", session.ID, session.UserAgent, session.IP)
+		}
+	}
+	if len(got) != 2 || !got[current.SessionID] || got[other.SessionID] {
+		t.Errorf("ListSessions() = %+v, want the current and the other session", list)
+	}
+}
+
+func TestTouch(t *testing.T) {
+	setup(t)
+	pair := mustCreateSession(t, "user")
+
+	if err := Touch(pair.SessionID, "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	list, err := ListSessions("user", pair.SessionID)
+	if err != nil || len(list) != 1 || list[0].IP != "10.0.0.1" {
+		t.Fatalf("ListSessions() after Touch() = %+v, %v, want the new ip", list, err)
+	}
+
+	// the session expires after the middleware checked it
+	if _, err := Store.Del(context.Background(), sessionKey(pair.SessionID)); err != nil {
+		t.Fatal(err)
+	}
+	if err := Touch(pair.SessionID, "10.0.0.1"); err != ErrSessionRevoked {
+		t.Errorf("Touch() of an expired session error = %v, want %v", err, ErrSessionRevoked)
+	}
+	if exists, err := Store.Exists(context.Background(), sessionKey(pair.SessionID)); err != nil || exists {
+		t.Errorf("Touch() stored the expired session again without its expiry")
+	}
+}
+
+func TestRevokeUserSession(t *testing.T) {
+	tests := []struct {
+		name      string
+		owner     string
+		revokedBy string
+		wantErr   error
+	}{
+		{"own session", "user", "user", nil},
+		{"session of someone else", "user", "attacker", ErrSessionNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setup(t)
+			pair := mustCreateSession(t, test.owner)
+
+			if err := RevokeUserSession(test.revokedBy, pair.SessionID); err != test.wantErr {
+				t.Fatalf("RevokeUserSession() error = %v, want %v", err, test.wantErr)
+			}
+			revoked, err := IsSessionRevoked(pair.SessionID)
+			if err != nil || revoked != (test.wantErr == nil) {
+				t.Errorf("IsSessionRevoked() = %v, %v", revoked, err)
+			}
+		})
+	}
+
+	t.Run("unknown session", func(t *testing.T) {
+		setup(t)
+		if err := RevokeUserSession("user", "unknown"); err != ErrSessionNotFound {
+			t.Errorf("RevokeUserSession() error = %v, want %v", err, ErrSessionNotFound)
+		}
+	})
+}
+
+func TestMFAChallenge(t *testing.T) {
+	setup(t)
+	token, err := CreateMFAChallenge("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for attempt := 1; attempt <= mfaChallengeAttempts; attempt++ {
+		if userID, err := MFAChallengeUser(token); err != nil || userID != "user" {
+			t.Fatalf("attempt %d: MFAChallengeUser() = %q, %v", attempt, userID, err)
+		}
+	}
+	if _, err := MFAChallengeUser(token); err != ErrInvalidMFAToken {
+		t.Errorf("MFAChallengeUser() after too many attempts error = %v, want %v", err, ErrInvalidMFAToken)
+	}
+
+	completed, err := CreateMFAChallenge("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteMFAChallenge(completed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MFAChallengeUser(completed); err != ErrInvalidMFAToken {
+		t.Errorf("MFAChallengeUser() of a completed challenge error = %v, want %v", err, ErrInvalidMFAToken)
+	}
+}
@@ -0,0 +1,267 @@
+package sessions
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+// keeps the storage in the sqlite database so sessions survive restarts of setups without redis, it is meant for
+// a single instance, strings are stored with an empty field and set members as fields with an empty value
+type sqliteStorage struct {
+	db  *sql.DB
+	now func() time.Time
+}
+
+// creates the table when it doesnt exist yet and removes expired keys every minute
+func NewSQLiteStorage(db *sql.DB) (Storage, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS session_storage (
+  Key TEXT NOT NULL,
+  Field TEXT NOT NULL,
+  Value TEXT NOT NULL,
+  ExpiresAt INTEGER,
+  PRIMARY KEY (Key, Field)
+)`)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS session_storage_expiresat ON session_storage (ExpiresAt)`); err != nil {
+		return nil, err
+	}
+
+	s := &sqliteStorage{db: db, now: time.Now}
+	go func() {
+		for range time.Tick(time.Minute) {
+			if _, err := s.db.Exec(`DELETE FROM session_storage WHERE ExpiresAt <= ?`, s.now().UnixMilli()); err != nil {
+				log.Println("Failed to remove expired sessions: " + err.Error())
+			}
+		}
+	}()
+	return s, nil
+}
+
+// every call runs in its own transaction, the database is opened with immediate transactions so they dont overlap
+// expired rows of the key are removed first so the rest of the call only sees live ones
+func (s *sqliteStorage) update(ctx context.Context, key string, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM session_storage WHERE Key = ? AND ExpiresAt <= ?`, key, s.now().UnixMilli()); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStorage) expiresAt(ttl time.Duration) any {
+	if ttl <= 0 {
+		return nil
+	}
+	return s.now().Add(ttl).UnixMilli()
+}
+
+// the ttl belongs to the key so new fields get the one the key already has
+func (s *sqliteStorage) setField(ctx context.Context, tx *sql.Tx, key, field string, value any) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO session_storage (Key, Field, Value, ExpiresAt)
+VALUES (?, ?, ?, (SELECT ExpiresAt FROM session_storage WHERE Key = ? LIMIT 1))
+ON CONFLICT (Key, Field) DO UPDATE SET Value = excluded.Value`, key, field, fmt.Sprint(value), key)
+	return err
+}
+
+func (s *sqliteStorage) field(ctx context.Context, tx *sql.Tx, key, field string) (string, bool, error) {
+	var value string
+	err := tx.QueryRowContext(ctx, `SELECT Value FROM session_storage WHERE Key = ? AND Field = ?`, key, field).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	return value, err == nil, err
+}
+
+func (s *sqliteStorage) increment(ctx context.Context, key, field string, increment int64) (int64, error) {
+	var count int64
+	err := s.update(ctx, key, func(tx *sql.Tx) error {
+		value, _, err := s.field(ctx, tx, key, field)
+		if err != nil {
+			return err
+		}
+		if value != "" {
+			if count, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return err
+			}
+		}
+		count += increment
+		return s.setField(ctx, tx, key, field, count)
+	})
+	return count, err
+}
+
+func (s *sqliteStorage) Get(ctx context.Context, key string) (string, bool, error) {
+	return s.HGet(ctx, key, "")
+}
+
+func (s *sqliteStorage) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return s.update(ctx, key, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM session_storage WHERE Key = ?`, key); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO session_storage (Key, Field, Value, ExpiresAt) VALUES (?, '', ?, ?)`, key, value, s.expiresAt(ttl))
+		return err
+	})
+}
+
+func (s *sqliteStorage) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	stored := false
+	err := s.update(ctx, key, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `INSERT INTO session_storage (Key, Field, Value, ExpiresAt)
+SELECT ?, '', ?, ? WHERE NOT EXISTS (SELECT 1 FROM session_storage WHERE Key = ?)`, key, value, s.expiresAt(ttl), key)
+		if err != nil {
+			return err
+		}
+		inserted, err := result.RowsAffected()
+		stored = inserted == 1
+		return err
+	})
+	return stored, err
+}
+
+func (s *sqliteStorage) GetDel(ctx context.Context, key string) (string, bool, error) {
+	var value string
+	var found bool
+	err := s.update(ctx, key, func(tx *sql.Tx) error {
+		var err error
+		if value, found, err = s.field(ctx, tx, key, ""); err != nil || !found {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM session_storage WHERE Key = ?`, key)
+		return err
+	})
+	return value, found, err
+}
+
+func (s *sqliteStorage) Del(ctx context.Context, keys ...string) (int64, error) {
+	var deleted int64
+	for _, key := range keys {
+		err := s.update(ctx, key, func(tx *sql.Tx) error {
+			result, err := tx.ExecContext(ctx, `DELETE FROM session_storage WHERE Key = ?`, key)
+			if err != nil {
+				return err
+			}
+			if rows, err := result.RowsAffected(); err == nil && rows > 0 {
+				deleted++
+			}
+			return err
+		})
+		if err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+func (s *sqliteStorage) Exists(ctx context.Context, key string) (bool, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM session_storage WHERE Key = ? AND (ExpiresAt IS NULL OR ExpiresAt > ?)`, key, s.now().UnixMilli()).Scan(&count)
+	return count > 0, err
+}
+
+func (s *sqliteStorage) Incr(ctx context.Context, key string) (int64, error) {
+	return s.increment(ctx, key, "", 1)
+}
+
+func (s *sqliteStorage) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return s.update(ctx, key, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `UPDATE session_storage SET ExpiresAt = ? WHERE Key = ?`, s.expiresAt(ttl), key)
+		return err
+	})
+}
+
+func (s *sqliteStorage) HSet(ctx context.Context, key string, values map[string]any) error {
+	return s.update(ctx, key, func(tx *sql.Tx) error {
+		for field, value := range values {
+			if err := s.setField(ctx, tx, key, field, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqliteStorage) HSetIfExists(ctx context.Context, key string, values map[string]any) (bool, error) {
+	set := false
+	err := s.update(ctx, key, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM session_storage WHERE Key = ?)`, key).Scan(&set); err != nil || !set {
+			return err
+		}
+		for field, value := range values {
+			if err := s.setField(ctx, tx, key, field, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return set, err
+}
+
+func (s *sqliteStorage) HGet(ctx context.Context, key, field string) (string, bool, error) {
+	var value string
+	err := s.db.QueryRowContext(ctx, `SELECT Value FROM session_storage WHERE Key = ? AND Field = ? AND (ExpiresAt IS NULL OR ExpiresAt > ?)`, key, field, s.now().UnixMilli()).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	return value, err == nil, err
+}
+
+func (s *sqliteStorage) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT Field, Value FROM session_storage WHERE Key = ? AND (ExpiresAt IS NULL OR ExpiresAt > ?)`, key, s.now().UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := map[string]string{}
+	for rows.Next() {
+		var field, value string
+		if err := rows.Scan(&field, &value); err != nil {
+			return nil, err
+		}
+		values[field] = value
+	}
+	return values, rows.Err()
+}
+
+func (s *sqliteStorage) HIncrBy(ctx context.Context, key, field string, increment int64) (int64, error) {
+	return s.increment(ctx, key, field, increment)
+}
+
+func (s *sqliteStorage) SAdd(ctx context.Context, key, member string) error {
+	return s.update(ctx, key, func(tx *sql.Tx) error {
+		return s.setField(ctx, tx, key, member, "")
+	})
+}
+
+func (s *sqliteStorage) SMembers(ctx context.Context, key string) ([]string, error) {
+	values, err := s.HGetAll(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	members := []string{}
+	for member := range values {
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+func (s *sqliteStorage) SRem(ctx context.Context, key, member string) error {
+	return s.update(ctx, key, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM session_storage WHERE Key = ? AND Field = ?`, key, member)
+		return err
+	})
+}
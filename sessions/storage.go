@@ -0,0 +1,131 @@
+package sessions
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// where sessions, revoked tokens, log in failures and one time tokens are kept, redis when it is running
+// and the sqlite database of setups without it, a key holds either a string, a hash or a set
+type Storage interface {
+	// found is false for missing and expired keys
+	Get(ctx context.Context, key string) (value string, found bool, err error)
+	// a ttl of 0 keeps the key until it is deleted
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	// sets the key only if it doesnt exist yet and tells if it did
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	GetDel(ctx context.Context, key string) (value string, found bool, err error)
+	// returns how many of the keys existed
+	Del(ctx context.Context, keys ...string) (int64, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	Incr(ctx context.Context, key string) (int64, error)
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+
+	HSet(ctx context.Context, key string, values map[string]any) error
+	// sets the fields only when the key exists so an expired key isnt recreated without its ttl, tells if it did
+	HSetIfExists(ctx context.Context, key string, values map[string]any) (bool, error)
+	HGet(ctx context.Context, key, field string) (value string, found bool, err error)
+	// an empty map for missing and expired keys
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+	HIncrBy(ctx context.Context, key, field string, increment int64) (int64, error)
+
+	SAdd(ctx context.Context, key, member string) error
+	SMembers(ctx context.Context, key string) ([]string, error)
+	SRem(ctx context.Context, key, member string) error
+}
+
+// set from main after connecting to redis or opening the sqlite database
+var Store Storage
+
+type redisStorage struct {
+	client *redis.Client
+}
+
+func NewRedisStorage(client *redis.Client) Storage {
+	return &redisStorage{client: client}
+}
+
+// redis reports missing keys as redis.Nil
+func redisResult[T any](value T, err error) (T, bool, error) {
+	if err == redis.Nil {
+		return value, false, nil
+	}
+	return value, err == nil, err
+}
+
+func (s *redisStorage) Get(ctx context.Context, key string) (string, bool, error) {
+	return redisResult(s.client.Get(ctx, key).Result())
+}
+
+func (s *redisStorage) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return s.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (s *redisStorage) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+func (s *redisStorage) GetDel(ctx context.Context, key string) (string, bool, error) {
+	return redisResult(s.client.GetDel(ctx, key).Result())
+}
+
+func (s *redisStorage) Del(ctx context.Context, keys ...string) (int64, error) {
+	return s.client.Del(ctx, keys...).Result()
+}
+
+func (s *redisStorage) Exists(ctx context.Context, key string) (bool, error) {
+	count, err := s.client.Exists(ctx, key).Result()
+	return count > 0, err
+}
+
+func (s *redisStorage) Incr(ctx context.Context, key string) (int64, error) {
+	return s.client.Incr(ctx, key).Result()
+}
+
+func (s *redisStorage) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return s.client.Expire(ctx, key, ttl).Err()
+}
+
+func (s *redisStorage) HSet(ctx context.Context, key string, values map[string]any) error {
+	return s.client.HSet(ctx, key, values).Err()
+}
+
+// checking and setting in one script so the key cant expire in between
+var hsetIfExists = redis.NewScript(`if redis.call("EXISTS", KEYS[1]) == 0 then return 0 end
+redis.call("HSET", KEYS[1], unpack(ARGV))
+return 1`)
+
+func (s *redisStorage) HSetIfExists(ctx context.Context, key string, values map[string]any) (bool, error) {
+	args := make([]any, 0, len(values)*2)
+	for field, value := range values {
+		args = append(args, field, value)
+	}
+	set, err := hsetIfExists.Run(ctx, s.client, []string{key}, args...).Int()
+	return set == 1, err
+}
+
+func (s *redisStorage) HGet(ctx context.Context, key, field string) (string, bool, error) {
+	return redisResult(s.client.HGet(ctx, key, field).Result())
+}
+
+func (s *redisStorage) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return s.client.HGetAll(ctx, key).Result()
+}
+
+func (s *redisStorage) HIncrBy(ctx context.Context, key, field string, increment int64) (int64, error) {
+	return s.client.HIncrBy(ctx, key, field, increment).Result()
+}
+
+func (s *redisStorage) SAdd(ctx context.Context, key, member string) error {
+	return s.client.SAdd(ctx, key, member).Err()
+}
+
+func (s *redisStorage) SMembers(ctx context.Context, key string) ([]string, error) {
+	return s.client.SMembers(ctx, key).Result()
+}
+
+func (s *redisStorage) SRem(ctx context.Context, key, member string) error {
+	return s.client.SRem(ctx, key, member).Err()
+}
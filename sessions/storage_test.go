@@ -0,0 +1,189 @@
+package sessions
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	_ "modernc.org/sqlite"
+)
+
+// every implementation with a way to move its clock forward
+func storages(t *testing.T) map[string]func(t *testing.T) (Storage, func(time.Duration)) {
+	return map[string]func(t *testing.T) (Storage, func(time.Duration)){
+		"redis": func(t *testing.T) (Storage, func(time.Duration)) {
+			server := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+			t.Cleanup(func() { client.Close() })
+			return NewRedisStorage(client), server.FastForward
+		},
+		"sqlite": func(t *testing.T) (Storage, func(time.Duration)) {
+			db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "sessions.db")+"?_txlock=immediate")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db.Close() })
+			storage, err := NewSQLiteStorage(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			now := time.Now()
+			storage.(*sqliteStorage).now = func() time.Time { return now }
+			return storage, func(d time.Duration) { now = now.Add(d) }
+		},
+	}
+}
+
+func TestStorageStrings(t *testing.T) {
+	for name, open := range storages(t) {
+		t.Run(name, func(t *testing.T) {
+			storage, _ := open(t)
+			ctx := context.Background()
+
+			if _, found, err := storage.Get(ctx, "a"); err != nil || found {
+				t.Fatalf("Get() of a missing key = %v, %v", found, err)
+			}
+			if err := storage.Set(ctx, "a", "1", 0); err != nil {
+				t.Fatal(err)
+			}
+			if value, found, err := storage.Get(ctx, "a"); err != nil || !found || value != "1" {
+				t.Errorf("Get() = %q, %v, %v, want 1", value, found, err)
+			}
+			if exists, err := storage.Exists(ctx, "a"); err != nil || !exists {
+				t.Errorf("Exists() = %v, %v, want true", exists, err)
+			}
+
+			if stored, err := storage.SetNX(ctx, "a", "2", 0); err != nil || stored {
+				t.Errorf("SetNX() of an existing key = %v, %v, want false", stored, err)
+			}
+			if stored, err := storage.SetNX(ctx, "b", "2", 0); err != nil || !stored {
+				t.Errorf("SetNX() of a new key = %v, %v, want true", stored, err)
+			}
+
+			if value, found, err := storage.GetDel(ctx, "a"); err != nil || !found || value != "1" {
+				t.Errorf("GetDel() = %q, %v, %v, want 1", value, found, err)
+			}
+			if _, found, err := storage.GetDel(ctx, "a"); err != nil || found {
+				t.Errorf("second GetDel() = %v, %v, want nothing", found, err)
+			}
+
+			if deleted, err := storage.Del(ctx, "a", "b"); err != nil || deleted != 1 {
+				t.Errorf("Del() = %d, %v, want 1", deleted, err)
+			}
+
+			for want := int64(1); want <= 3; want++ {
+				if count, err := storage.Incr(ctx, "count"); err != nil || count != want {
+					t.Errorf("Incr() = %d, %v, want %d", count, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStorageHashesAndSets(t *testing.T) {
+	for name, open := range storages(t) {
+		t.Run(name, func(t *testing.T) {
+			storage, _ := open(t)
+			ctx := context.Background()
+
+			if err := storage.HSet(ctx, "hash", map[string]any{"name": "frog", "count": 1}); err != nil {
+				t.Fatal(err)
+			}
+			if err := storage.HSet(ctx, "hash", map[string]any{"name": "toad"}); err != nil {
+				t.Fatal(err)
+			}
+			if value, found, err := storage.HGet(ctx, "hash", "name"); err != nil || !found || value != "toad" {
+				t.Errorf("HGet() = %q, %v, %v, want toad", value, found, err)
+			}
+			if _, found, err := storage.HGet(ctx, "hash", "missing"); err != nil || found {
+				t.Errorf("HGet() of a missing field = %v, %v", found, err)
+			}
+			if set, err := storage.HSetIfExists(ctx, "hash", map[string]any{"color": "green"}); err != nil || !set {
+				t.Errorf("HSetIfExists() = %v, %v, want true", set, err)
+			}
+			if set, err := storage.HSetIfExists(ctx, "missing", map[string]any{"color": "green"}); err != nil || set {
+				t.Errorf("HSetIfExists() of a missing key = %v, %v, want false", set, err)
+			}
+			if exists, err := storage.Exists(ctx, "missing"); err != nil || exists {
+				t.Errorf("HSetIfExists() created the missing key")
+			}
+			if count, err := storage.HIncrBy(ctx, "hash", "count", 2); err != nil || count != 3 {
+				t.Errorf("HIncrBy() = %d, %v, want 3", count, err)
+			}
+			values, err := storage.HGetAll(ctx, "hash")
+			if err != nil || len(values) != 3 || values["name"] != "toad" || values["count"] != "3" || values["color"] != "green" {
+				t.Errorf("HGetAll() = %v, %v", values, err)
+			}
+			if values, err := storage.HGetAll(ctx, "missing"); err != nil || len(values) != 0 {
+				t.Errorf("HGetAll() of a missing key = %v, %v, want an empty map", values, err)
+			}
+
+			for _, member := range []string{"a", "b", "a"} {
+				if err := storage.SAdd(ctx, "set", member); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := storage.SRem(ctx, "set", "b"); err != nil {
+				t.Fatal(err)
+			}
+			if members, err := storage.SMembers(ctx, "set"); err != nil || !slices.Equal(members, []string{"a"}) {
+				t.Errorf("SMembers() = %v, %v, want [a]", members, err)
+			}
+		})
+	}
+}
+
+func TestStorageExpiry(t *testing.T) {
+	for name, open := range storages(t) {
+		t.Run(name, func(t *testing.T) {
+			storage, advance := open(t)
+			ctx := context.Background()
+
+			if err := storage.Set(ctx, "token", "1", time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			if err := storage.HSet(ctx, "hash", map[string]any{"a": 1}); err != nil {
+				t.Fatal(err)
+			}
+			if err := storage.Expire(ctx, "hash", time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			// fields added later expire with the hash
+			if err := storage.HSet(ctx, "hash", map[string]any{"b": 2}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := storage.Incr(ctx, "count"); err != nil {
+				t.Fatal(err)
+			}
+			if err := storage.Expire(ctx, "count", time.Minute); err != nil {
+				t.Fatal(err)
+			}
+
+			advance(2 * time.Minute)
+
+			if _, found, err := storage.Get(ctx, "token"); err != nil || found {
+				t.Errorf("Get() of an expired key = %v, %v", found, err)
+			}
+			if exists, err := storage.Exists(ctx, "token"); err != nil || exists {
+				t.Errorf("Exists() of an expired key = %v, %v", exists, err)
+			}
+			if stored, err := storage.SetNX(ctx, "token", "2", 0); err != nil || !stored {
+				t.Errorf("SetNX() of an expired key = %v, %v, want true", stored, err)
+			}
+			if set, err := storage.HSetIfExists(ctx, "hash", map[string]any{"c": 3}); err != nil || set {
+				t.Errorf("HSetIfExists() of an expired hash = %v, %v, want false", set, err)
+			}
+			if values, err := storage.HGetAll(ctx, "hash"); err != nil || len(values) != 0 {
+				t.Errorf("HGetAll() of an expired hash = %v, %v", values, err)
+			}
+			if count, err := storage.Incr(ctx, "count"); err != nil || count != 1 {
+				t.Errorf("Incr() of an expired key = %d, %v, want 1", count, err)
+			}
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"html/template"
 	"net/smtp"
+	"net/url"
+	"time"
 
 	"github.com/froggy-12/mooshroombase_v2/configs"
 )
@@ -70,6 +72,55 @@ func SendVerificationEmail(emailTo string, code string) error {
 	return nil
 }
 
+type PasswordResetEmailData struct {
+	Token            string
+	Link             string
+	ExpiresInMinutes int
+}
+
+func SendPasswordResetEmail(emailTo string, token string) error {
+	data := PasswordResetEmailData{
+		Token:            token,
+		ExpiresInMinutes: configs.Configs.Authentication.PasswordResetTokenExpiration,
+	}
+	if configs.Configs.Authentication.PasswordResetURL != "" {
+		data.Link = configs.Configs.Authentication.PasswordResetURL + "?token=" + url.QueryEscape(token)
+	}
+
+	tmpl := template.Must(template.New("email").Parse(`	<!DOCTYPE html>
+<html lang="en">
+
+<head>
+
+  <meta charset="UTF-8">
+  <meta http-equiv="X-UA-Compatible" content="IE=edge">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0">
+  <title>Password Reset</title>
+</head>
+
+<body>
+  <div>
+    <h1>Lets reset your password 🔑</h1>
+    {{ if .Link }}
+    <h1><a href="{{ .Link }}">Click here to reset your password</a></h1>
+    {{ else }}
+    <h1>Your Token is <span>{{ .Token }}</span></h1>
+    {{ end }}
+    <p>It expires in {{ .ExpiresInMinutes }} minutes. If you didnt ask for a password reset you can ignore this email</p>
+  </div>
+</body>
+
+</html>`))
+
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, data)
+	if err != nil {
+		return err
+	}
+
+	return SendEmailWithAnything("Password Reset", emailTo, buf.String())
+}
+
 func SendEmailWithAnything(EmailSubject, emailTo, emailBody string) error {
 	smtpServer := configs.Configs.SMTPConfigurations.SMTPServerAddress
 	smtpPort := configs.Configs.SMTPConfigurations.SMTPServerPORT
@@ -93,3 +144,81 @@ func SendEmailWithAnything(EmailSubject, emailTo, emailBody string) error {
 
 	return nil
 }
+
+type MagicLinkEmailData struct {
+	Link             string
+	ExpiresInMinutes int
+}
+
+func SendMagicLinkEmail(emailTo string, link string) error {
+	data := MagicLinkEmailData{
+		Link:             link,
+		ExpiresInMinutes: configs.Configs.Authentication.MagicLinkTokenExpiration,
+	}
+
+	tmpl := template.Must(template.New("email").Parse(`	<!DOCTYPE html>
+<html lang="en">
+
+<head>
+
+  <meta charset="UTF-8">
+  <meta http-equiv="X-UA-Compatible" content="IE=edge">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0">
+  <title>Log In</title>
+</head>
+
+<body>
+  <div>
+    <h1>Here is your log in link 🪄</h1>
+    <h1><a href="{{ .Link }}">Click here to log in</a></h1>
+    <p>It expires in {{ .ExpiresInMinutes }} minutes and can only be used once. If you didnt try to log in you can ignore this email</p>
+  </div>
+</body>
+
+</html>`))
+
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, data)
+	if err != nil {
+		return err
+	}
+
+	return SendEmailWithAnything("Log In Link", emailTo, buf.String())
+}
+
+type AccountLockedEmailData struct {
+	Until string
+}
+
+func SendAccountLockedEmail(emailTo string, until time.Time) error {
+	data := AccountLockedEmailData{Until: until.UTC().Format("2006-01-02 15:04 MST")}
+
+	tmpl := template.Must(template.New("email").Parse(`	<!DOCTYPE html>
+<html lang="en">
+
+<head>
+
+  <meta charset="UTF-8">
+  <meta http-equiv="X-UA-Compatible" content="IE=edge">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0">
+  <title>Account Locked</title>
+</head>
+
+<body>
+  <div>
+    <h1>Your account has been locked 🔒</h1>
+    <p>There were too many failed log in attempts on your account so it is locked until {{ .Until }}.</p>
+    <p>If this wasnt you someone might be trying to guess your password, please change it after the lock expires</p>
+  </div>
+</body>
+
+</html>`))
+
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, data)
+	if err != nil {
+		return err
+	}
+
+	return SendEmailWithAnything("Account Locked", emailTo, buf.String())
+}
@@ -1,7 +1,6 @@
 package types
 
 import (
-	"database/sql"
 	"time"
 )
 
@@ -39,37 +38,40 @@ type User struct {
 	ProfilePicture string `json:"profilePicture"`
 }
 
-type UpdateMongoUser struct {
-	FirstName      string        `json:"firstName"`
-	LastName       string        `json:"lastName"`
-	ProfilePicture string        `json:"profilePicture"`
-	RawData        []RawUserData `json:"rawData"`
-}
-
-type UpdateMariaUser struct {
-	FirstName      string `json:"firstName"`
-	LastName       string `json:"lastName"`
-	ProfilePicture string `json:"profilePicture"`
+// details of the update-user-info route, empty fields are left as they are
+type UpdateUserDetails struct {
+	FirstName      string         `json:"firstName"`
+	LastName       string         `json:"lastName"`
+	ProfilePicture string         `json:"profilePicture"`
+	RawData        []RawUserData  `json:"rawData"`
+	ProfileFields  map[string]any `json:"profileFields"` // checked against the configured profile fields, null removes a field
 }
 
 type UpdateMongoUserRawData struct {
 	RawData RawUserData `json:"rawData"`
 }
 
-type User_Mongo struct {
+// a user as every user store reads and writes it, the mongodb documents have exactly this shape
+type AuthUser struct {
 	ID                string           `bson:"id"`
-	UserName          string           `bson:"username, unique"`
+	UserName          string           `bson:"username"`
 	FirstName         string           `bson:"firstName"`
 	LastName          string           `bson:"lastName"`
-	Email             string           `bson:"email, unique"`
-	Password          string           `bson:"password"`
+	Email             string           `bson:"email"`
+	Password          string           `bson:"password" json:"-"`
 	ProfilePicture    string           `bson:"profilePicture"`
 	CreatedAt         time.Time        `bson:"createdAt"`
 	UpdatedAt         time.Time        `bson:"updatedAt"`
 	Verified          bool             `bson:"verified"`
-	VerificationToken string           `bson:"verificationToken"`
+	VerificationToken string           `bson:"verificationToken" json:"-"`
 	LastLoggedIn      LastTimeLoggedIn `bson:"lastLoggedIn"`
 	RawData           []RawUserData    `bson:"rawData"`
+	ProfileFields     map[string]any   `bson:"profileFields"`
+	OAuthAccounts     []OAuthAccount   `bson:"oauthAccounts"`
+	Roles             []string         `bson:"roles"`
+	Disabled          bool             `bson:"disabled"`
+	DisabledReason    string           `bson:"disabledReason"`
+	Guest             bool             `bson:"guest"`
 }
 
 type LastTimeLoggedIn struct {
@@ -80,34 +82,159 @@ type RawUserData struct {
 	Data map[string]any `bson:"data"`
 }
 
-type User_Maria struct {
-	ID                string
-	UserName          string
-	FirstName         string
-	LastName          string
-	Email             string
-	Password          string
-	ProfilePicture    string
-	CreatedAt         sql.NullTime
-	UpdatedAt         sql.NullTime
-	Verified          bool
-	VerificationToken string
-	LastLoggedIn      sql.NullTime
-}
-
-type User_Mongo_Oauth struct {
-	ID             string    `bson:"id"`
-	UserName       string    `bson:"username, unique"`
-	FirstName      string    `bson:"firstName"`
-	LastName       string    `bson:"lastName"`
-	Email          string    `bson:"email, unique"`
-	ProfilePicture string    `bson:"profilePicture"`
-	Verified       bool      `bson:"verified"`
-	CreatedAt      time.Time `bson:"createdAt"`
-	UpdatedAt      time.Time `bson:"updatedAt"`
+// account of an oauth provider linked to a user
+type OAuthAccount struct {
+	Provider       string    `bson:"provider" json:"provider"`
+	ProviderUserID string    `bson:"providerUserId" json:"providerUserId"`
+	Email          string    `bson:"email" json:"email"`
+	LinkedAt       time.Time `bson:"linkedAt" json:"linkedAt"`
+}
+
+// user information received from an oauth provider after successful authorization
+type OAuthUserProfile struct {
+	Provider       string
+	ProviderUserID string
+	UserName       string
+	FirstName      string
+	LastName       string
+	Email          string
+	EmailVerified  bool
+	ProfilePicture string
+}
+
+type PasswordReset struct {
+	TokenHash string    `bson:"tokenHash"`
+	UserID    string    `bson:"userId"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+	CreatedAt time.Time `bson:"createdAt"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type MagicLinkVerifyDetails struct {
+	Token string `json:"token" form:"token" validate:"required"`
+}
+
+type ResetPasswordDetails struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required,min=8"`
+}
+
+type ChangePasswordDetails struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword" validate:"required,min=8"`
+}
+
+// totp secret of a user, it only counts once the enrollment has been confirmed with a code
+type TwoFactor struct {
+	UserID        string    `bson:"userId"`
+	Secret        string    `bson:"secret"`
+	Enabled       bool      `bson:"enabled"`
+	RecoveryCodes []string  `bson:"recoveryCodes"` // sha256 hashes
+	CreatedAt     time.Time `bson:"createdAt"`
+}
+
+type TwoFactorCode struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type TwoFactorLogInDetails struct {
+	MFAToken string `json:"mfaToken" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// a webauthn credential of a user, the id is the base64url encoded credential id
+type Passkey struct {
+	ID              string    `bson:"id" json:"id"`
+	UserID          string    `bson:"userId" json:"-"`
+	Name            string    `bson:"name" json:"name"`
+	PublicKey       []byte    `bson:"publicKey" json:"-"`
+	AttestationType string    `bson:"attestationType" json:"-"`
+	Transports      []string  `bson:"transports" json:"transports"`
+	SignCount       uint32    `bson:"signCount" json:"-"`
+	AAGUID          []byte    `bson:"aaguid" json:"-"`
+	BackupEligible  bool      `bson:"backupEligible" json:"backupEligible"`
+	BackupState     bool      `bson:"backupState" json:"backupState"`
+	CreatedAt       time.Time `bson:"createdAt" json:"createdAt"`
+	LastUsedAt      time.Time `bson:"lastUsedAt" json:"lastUsedAt"`
+}
+
+// key for server to server access, the key itself is only shown once when it is created
+type APIKey struct {
+	ID         string     `bson:"id" json:"id"`
+	Name       string     `bson:"name" json:"name"`
+	KeyHash    string     `bson:"keyHash" json:"-"`
+	Prefix     string     `bson:"prefix" json:"prefix"`
+	Scopes     []string   `bson:"scopes" json:"scopes"`
+	UserID     string     `bson:"userId" json:"userId"`
+	CreatedAt  time.Time  `bson:"createdAt" json:"createdAt"`
+	ExpiresAt  *time.Time `bson:"expiresAt" json:"expiresAt"`
+	LastUsedAt *time.Time `bson:"lastUsedAt" json:"lastUsedAt"`
+}
+
+type CreateAPIKeyDetails struct {
+	Name      string     `json:"name" validate:"required,max=255"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1"`
+	UserID    string     `json:"userId"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// credentials a guest account is upgraded with, the names of the guest are kept when they are empty
+type UpgradeGuestDetails struct {
+	UserName  string `json:"username" validate:"required"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email" validate:"required,email"`
+	Password  string `json:"password" validate:"required,min=8"`
 }
 
 type LogInDetails struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=8"`
 }
+
+// claims of an access token
+type AccessTokenClaims struct {
+	UserID    string
+	JTI       string
+	SessionID string
+	Roles     []string
+	IssuedAt  time.Time
+	// signed before sessions existed, it has no session and can only be exchanged for one at /api/auth/refresh
+	Legacy bool
+}
+
+type UserRolesDetails struct {
+	Roles []string `json:"roles" validate:"required"`
+}
+
+type DisableUserDetails struct {
+	Reason string `json:"reason"`
+}
+
+type AdminPasswordDetails struct {
+	NewPassword string `json:"newPassword" validate:"required,min=8"`
+}
+
+// lockout state of an account after failed log in attempts
+type LockStatus struct {
+	Locked         bool      `json:"locked"`
+	LockedUntil    time.Time `json:"lockedUntil"`
+	FailedAttempts int       `json:"failedAttempts"`
+}
+
+// a logged in device of the user
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	Current    bool      `json:"current"`
+}
@@ -1,8 +1,9 @@
 package utils
 
 import (
-	"context"
-	"database/sql"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -13,14 +14,13 @@ import (
 	"strings"
 	"time"
 
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/froggy-12/mooshroombase_v2/jwtkeys"
+	"github.com/froggy-12/mooshroombase_v2/sessions"
 	"github.com/froggy-12/mooshroombase_v2/types"
-	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"golang.org/x/crypto/bcrypt"
 )
 
 var DebugLogging bool
@@ -129,55 +129,71 @@ func UploadAnyFile(_ *fiber.Ctx, file *multipart.FileHeader, folder, filename st
 	return nil
 }
 
-func GenerateJWTToken(id string, jwtExpirationTime int, jwtSecret string) (string, error) {
-	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub":  id,
-		"expr": time.Now().Add(time.Hour * 24 * time.Duration(jwtExpirationTime)).Unix(),
-		"iat":  time.Now().Unix(),
-	})
-
-	token, err := claims.SignedString([]byte(jwtSecret))
-
-	if err != nil {
-		return "", err
+// checks the signature and the registered claims of an access token, an expired token is told apart from an invalid one
+// so its claims can still be read
+func parseAccessToken(token string) (jwt.MapClaims, bool, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, jwtkeys.Keyfunc,
+		jwt.WithValidMethods(jwtkeys.ValidMethods()),
+		jwt.WithIssuer(configs.Configs.HttpConfigurations.JWTIssuer),
+		jwt.WithAudience(configs.Configs.HttpConfigurations.JWTAudience),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
+	if err == nil {
+		return claims, false, nil
 	}
 
-	return token, nil
+	// claims are only validated after the signature so the token is genuine when one of them fails
+	if errors.Is(err, jwt.ErrTokenInvalidClaims) {
+		if _, legacy := claims["expr"]; legacy && claims["exp"] == nil {
+			return legacyAccessTokenClaims(claims)
+		}
+		if onlyExpired(err) {
+			return claims, true, nil
+		}
+	}
+	return nil, false, err
 }
 
-func RefreshJWTToken(token, jwtSecret string, jwtExpirationTime int) (string, error) {
-	claims := jwt.MapClaims{}
-	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
-		return []byte(jwtSecret), nil
-	})
-
-	if err != nil {
-		return "", err
+// the validator reports every failed claim, the token is only expired when no other check failed
+func onlyExpired(err error) bool {
+	if !errors.Is(err, jwt.ErrTokenExpired) {
+		return false
 	}
+	for _, other := range []error{jwt.ErrTokenNotValidYet, jwt.ErrTokenUsedBeforeIssued, jwt.ErrTokenInvalidIssuer, jwt.ErrTokenInvalidAudience, jwt.ErrTokenRequiredClaimMissing, jwt.ErrInvalidType} {
+		if errors.Is(err, other) {
+			return false
+		}
+	}
+	return true
+}
 
-	userId, ok := claims["sub"].(string)
-	if !ok {
-		return "", errors.New("invalid token claims")
+// tokens signed before the registered claims carry expr instead of exp and have no issuer or audience
+// they are only accepted until JWTLegacyTokensUntil and only to be exchanged once for a session
+func legacyAccessTokenClaims(claims jwt.MapClaims) (jwt.MapClaims, bool, error) {
+	deadline, err := time.Parse(time.RFC3339, configs.Configs.HttpConfigurations.JWTLegacyTokensUntil)
+	if err != nil || time.Now().After(deadline) {
+		return nil, false, errors.New("tokens with the old claims are no longer accepted")
 	}
 
-	// Generate a new token with the same user ID and a new expiration time
-	newToken, err := GenerateJWTToken(userId, jwtExpirationTime, jwtSecret)
-	if err != nil {
-		return "", err
+	expr, ok := claims["expr"].(float64)
+	if !ok {
+		return nil, false, errors.New("invalid token claims")
 	}
 
-	return newToken, nil
+	return claims, time.Now().After(time.Unix(int64(expr), 0)), nil
 }
 
-func ExtractJWTToken(token, jwtSecret string) (string, error) {
-	claims := jwt.MapClaims{}
-	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
-		return []byte(jwtSecret), nil
-	})
+func ExtractJWTToken(token string) (string, error) {
+	claims, expired, err := parseAccessToken(token)
 
 	if err != nil {
 		return "", err
 	}
+	if expired {
+		return "", errors.New("token has expired")
+	}
 
 	userID, ok := claims["sub"].(string)
 	if !ok {
@@ -187,219 +203,152 @@ func ExtractJWTToken(token, jwtSecret string) (string, error) {
 	return userID, nil
 }
 
-func ReadJWTToken(token, jwtSecret string) (string, bool, error) {
-	claims := jwt.MapClaims{}
-	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
-		return []byte(jwtSecret), nil
-	})
+// reads the claims of an access token, expired tokens return their claims with expired set so the caller can tell them apart
+func ParseAccessToken(token string) (types.AccessTokenClaims, bool, error) {
+	claims, expired, err := parseAccessToken(token)
 
 	if err != nil {
-		return "", false, err
+		return types.AccessTokenClaims{}, false, err
 	}
 
-	userId, ok := claims["sub"].(string)
+	userID, ok := claims["sub"].(string)
 	if !ok {
-		return "", false, errors.New("invalid token claims")
+		return types.AccessTokenClaims{}, false, errors.New("invalid token claims")
 	}
-	expr, ok := claims["expr"].(float64)
+	iat, ok := claims["iat"].(float64)
 	if !ok {
-		return "", false, errors.New("invalid token claims")
-	}
-
-	expirationTime := time.Unix(int64(expr), 0)
-	if time.Now().After(expirationTime) {
-		return "", true, nil
+		return types.AccessTokenClaims{}, false, errors.New("invalid token claims")
+	}
+
+	accessClaims := types.AccessTokenClaims{UserID: userID, IssuedAt: time.Unix(int64(iat), 0), Roles: []string{}}
+	accessClaims.JTI, _ = claims["jti"].(string)
+	accessClaims.SessionID, _ = claims["sid"].(string)
+	_, accessClaims.Legacy = claims["expr"]
+	if roles, ok := claims["roles"].([]any); ok {
+		for _, role := range roles {
+			if role, ok := role.(string); ok {
+				accessClaims.Roles = append(accessClaims.Roles, role)
+			}
+		}
 	}
 
-	return userId, false, nil
+	return accessClaims, expired, nil
 }
 
-func SetJwtHttpCookies(c *fiber.Ctx, token string, cookieAge int) {
-	expires := time.Now().Add(time.Hour * 24 * time.Duration(cookieAge))
-	maxAge := int(expires.Sub(time.Now()).Seconds())
-	cookie := &fiber.Cookie{
-		Name:     "jwtToken",
-		Value:    token,
-		Path:     "/",
-		HTTPOnly: true,
-		Secure:   true,
-		MaxAge:   maxAge,
+// generates a random hex encoded token with the given amount of random bytes
+func GenerateRandomToken(size int) (string, error) {
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
 	}
-
-	c.Cookie(cookie)
-}
-
-func FindUserFromMongoDBUsingEmail(email string, mongoCollection *mongo.Collection) (types.User_Mongo, error) {
-	filter := bson.M{"email": email}
-	user := types.User_Mongo{}
-	err := mongoCollection.FindOne(context.Background(), filter).Decode(&user)
-	return user, err
-}
-
-func FindUserFromMongoDBUsingUsername(username string, mongoCollection *mongo.Collection) (types.User_Mongo, error) {
-	filter := bson.M{"username": username}
-	user := types.User_Mongo{}
-	err := mongoCollection.FindOne(context.Background(), filter).Decode(&user)
-	return user, err
+	return hex.EncodeToString(bytes), nil
 }
 
-func FindUserFromMongoDBUsingID(id string, mongoCollection *mongo.Collection) (types.User_Mongo, error) {
-	filter := bson.M{"id": id}
-	user := types.User_Mongo{}
-	err := mongoCollection.FindOne(context.Background(), filter).Decode(&user)
-	return user, err
+// tokens which are sent to users are stored as sha256 hashes so a leaked database doesnt leak usable tokens
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
-func LogIn(c *fiber.Ctx, coll *mongo.Collection, validate validator.Validate, jwtExpirationTime int, jwtSecret string) error {
-	var details types.LogInDetails
-	if err := c.BodyParser(&details); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Request body"})
-	}
-
-	if err := validate.Struct(&details); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
-	}
-
-	user, err := FindUserFromMongoDBUsingEmail(details.Email, coll)
-
-	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User Doesnt Exist"})
-	}
-
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(details.Password))
-
-	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Wrong Password"})
+// the access token of the request, sent as the cookie or as Authorization: Bearer <jwt>
+func AccessToken(c *fiber.Ctx) string {
+	if token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer "); ok && token != "" {
+		return token
 	}
+	return c.Cookies(sessions.AccessTokenCookieName)
+}
 
-	token, err := GenerateJWTToken(user.ID, jwtExpirationTime, jwtSecret)
-
-	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to generate JWT token"})
+// id of the user making the request, the auth middleware sets it from the access token or the api key
+func CurrentUserID(c *fiber.Ctx) (string, error) {
+	if userId, ok := c.Locals("userId").(string); ok && userId != "" {
+		return userId, nil
 	}
+	return ExtractJWTToken(AccessToken(c))
+}
 
-	lastLoggedIn := types.LastTimeLoggedIn{
-		When: time.Now(),
-	}
-
-	_, err = coll.UpdateOne(context.Background(), bson.M{"id": user.ID}, bson.M{"$set": bson.M{"lastLoggedIn": lastLoggedIn}})
-
-	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Something Went Wrong: " + err.Error()})
+// adds the tokens of a new session to the response data when the client asked for them
+func WithTokens(c *fiber.Ctx, pair sessions.TokenPair, data map[string]any) map[string]any {
+	if sessions.TokensInBody(c) && pair.AccessToken != "" {
+		data["accessToken"] = pair.AccessToken
+		data["refreshToken"] = pair.RefreshToken
 	}
+	return data
+}
 
-	SetJwtHttpCookies(c, token, jwtExpirationTime)
+// response of a successful log in
+func LogInResponse(c *fiber.Ctx, userID string, pair sessions.TokenPair) error {
 	return c.Status(http.StatusAccepted).JSON(types.HttpSuccessResponse{
 		Message: "User has been logged in successfully",
-		Data:    map[string]any{"userID": user.ID},
+		Data:    WithTokens(c, pair, map[string]any{"userID": userID}),
 	})
-
 }
 
-func FindUserFromMariaDBUsingEmail(email string, db *sql.DB) (types.User_Maria, error) {
-	var user types.User_Maria
-	query := "select * from mooshroombase.users where Email = ?;"
-	err := db.QueryRow(query, email).Scan(
-		&user.ID,
-		&user.UserName,
-		&user.FirstName,
-		&user.LastName,
-		&user.Email,
-		&user.Password,
-		&user.ProfilePicture,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-		&user.Verified,
-		&user.VerificationToken,
-		&user.LastLoggedIn,
-	)
-
-	return user, err
+// revokes the current session and starts a new one, used after an upgrade so the access token gets the new roles
+func ReplaceSession(c *fiber.Ctx, userID string) (sessions.TokenPair, error) {
+	sessionID, _ := c.Locals("sessionId").(string)
+	if sessionID == "" {
+		if claims, _, err := ParseAccessToken(AccessToken(c)); err == nil {
+			sessionID = claims.SessionID
+		}
+	}
+	if sessionID != "" {
+		if err := sessions.RevokeSession(sessionID); err != nil {
+			return sessions.TokenPair{}, err
+		}
+	}
+	return sessions.LogIn(c, userID)
 }
 
-func FindUserFromMariaDBUsingID(ID string, db *sql.DB) (types.User_Maria, error) {
-	var user types.User_Maria
-	query := "select * from mooshroombase.users where ID = ?;"
-	err := db.QueryRow(query, ID).Scan(
-		&user.ID,
-		&user.UserName,
-		&user.FirstName,
-		&user.LastName,
-		&user.Email,
-		&user.Password,
-		&user.ProfilePicture,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-		&user.Verified,
-		&user.VerificationToken,
-		&user.LastLoggedIn,
-	)
-
-	return user, err
+// guests who never upgrade are deleted after this, configs without it get 30 days
+func GuestAccountLifetime() time.Duration {
+	if configs.Configs.Authentication.GuestAccountLifetime <= 0 {
+		return time.Hour * 24 * 30
+	}
+	return time.Hour * 24 * time.Duration(configs.Configs.Authentication.GuestAccountLifetime)
 }
 
-func FindUserFromMariaDBUsingUsername(username string, db *sql.DB) (types.User_Maria, error) {
-	var user types.User_Maria
-	query := "select * from mooshroombase.users where UserName = ?;"
-	err := db.QueryRow(query, username).Scan(
-		&user.ID,
-		&user.UserName,
-		&user.FirstName,
-		&user.LastName,
-		&user.Email,
-		&user.Password,
-		&user.ProfilePicture,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-		&user.Verified,
-		&user.VerificationToken,
-		&user.LastLoggedIn,
-	)
-
-	return user, err
+// emails and usernames are unique so every guest gets placeholders made from its id, the .invalid domain never receives mail
+func GuestEmail(userID string) string {
+	return userID + "@guest.invalid"
 }
 
-func LogInMariaDB(c *fiber.Ctx, db *sql.DB, validate validator.Validate, jwtExpirationTime int, jwtSecret string) error {
-	var details types.LogInDetails
-	if err := c.BodyParser(&details); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Invalid Request body"})
-	}
+func GuestUserName(userID string) string {
+	return "guest-" + userID
+}
 
-	if err := validate.Struct(&details); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: err.Error()})
+// response for a log in whose session couldnt be created, disabled users get a 403
+func SessionError(c *fiber.Ctx, err error) error {
+	if err == sessions.ErrUserDisabled {
+		return c.Status(http.StatusForbidden).JSON(types.ErrorResponse{Error: "User has been disabled"})
 	}
+	return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to create the session: " + err.Error()})
+}
 
-	user, err := FindUserFromMariaDBUsingEmail(details.Email, db)
-
+// the password was right but the totp code is still missing, the client has to send it with the returned token to /api/auth/verify-2fa
+func TwoFactorChallenge(c *fiber.Ctx, userID string) error {
+	token, err := sessions.CreateMFAChallenge(userID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "User Doesnt Exist"})
-		} else {
-			return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Something went wrong: " + err.Error()})
-		}
+		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to create two factor challenge: " + err.Error()})
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(details.Password))
+	return c.Status(http.StatusAccepted).JSON(types.HttpSuccessResponse{
+		Message: "Two factor authentication is required",
+		Data:    map[string]any{"mfaRequired": true, "mfaToken": token},
+	})
+}
 
-	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Wrong Password"})
+// page and limit query params of the listing routes, limit is at most 100
+func Pagination(c *fiber.Ctx) (int, int) {
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
 	}
-
-	token, err := GenerateJWTToken(user.ID, jwtExpirationTime, jwtSecret)
-
-	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(types.ErrorResponse{Error: "Failed to generate JWT token"})
+	limit := c.QueryInt("limit", 20)
+	if limit < 1 {
+		limit = 20
 	}
-
-	_, err = db.Exec(`UPDATE mooshroombase.users SET LastLoggedIn = CURRENT_TIMESTAMP() WHERE ID = ?`, user.ID)
-
-	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(types.ErrorResponse{Error: "Something Went Wrong: " + err.Error()})
+	if limit > 100 {
+		limit = 100
 	}
-
-	SetJwtHttpCookies(c, token, jwtExpirationTime)
-	return c.Status(http.StatusAccepted).JSON(types.HttpSuccessResponse{
-		Message: "User has been logged in successfully",
-		Data:    map[string]any{"userID": user.ID},
-	})
+	return page, limit
 }
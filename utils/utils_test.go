@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/froggy-12/mooshroombase_v2/configs"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func useTokenConfig(t *testing.T, legacyUntil string) {
+	t.Helper()
+	previous := configs.Configs.HttpConfigurations
+	configs.Configs.HttpConfigurations = configs.HttpConfigurations{
+		JWTSecret:            "secret",
+		JWTIssuer:            "mooshroombase",
+		JWTAudience:          "mooshroombase",
+		JWTLegacyTokensUntil: legacyUntil,
+	}
+	t.Cleanup(func() { configs.Configs.HttpConfigurations = previous })
+}
+
+func signed(t *testing.T, method jwt.SigningMethod, key any, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// claims of an access token which changes are applied to, a nil value removes the claim
+func tokenClaims(changes jwt.MapClaims) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub": "user",
+		"jti": "jti",
+		"sid": "session",
+		"iss": "mooshroombase",
+		"aud": "mooshroombase",
+		"exp": now.Add(time.Minute).Unix(),
+		"nbf": now.Unix(),
+		"iat": now.Unix(),
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+	}
+	return claims
+}
+
+func TestParseAccessToken(t *testing.T) {
+	past, future := time.Now().Add(-time.Hour).Format(time.RFC3339), time.Now().Add(time.Hour).Format(time.RFC3339)
+	hour := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name        string
+		legacyUntil string
+		token       func(t *testing.T) string
+		wantExpired bool
+		wantLegacy  bool
+		wantErr     bool
+	}{
+		{
+			name: "valid token",
+			token: func(t *testing.T) string {
+				return signed(t, jwt.SigningMethodHS256, []byte("secret"), tokenClaims(nil))
+			},
+		},
+		{
+			name: "expired token",
+			token: func(t *testing.T) string {
+				return signed(t, jwt.SigningMethodHS256, []byte("secret"), tokenClaims(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}))
+			},
+			wantExpired: true,
+		},
+		{
+			name: "other secret",
+			token: func(t *testing.T) string {
+				return signed(t, jwt.SigningMethodHS256, []byte("other"), tokenClaims(nil))
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsigned token",
+			token: func(t *testing.T) string {
+				return signed(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, tokenClaims(nil))
+			},
+			wantErr: true,
+		},
+		{
+			name: "other issuer",
+			token: func(t *testing.T) string {
+				return signed(t, jwt.SigningMethodHS256, []byte("secret"), tokenClaims(jwt.MapClaims{"iss": "someone else"}))
+			},
+			wantErr: true,
+		},
+		{
+			name: "other audience",
+			token: func(t *testing.T) string {
+				return signed(t, jwt.SigningMethodHS256, []byte("secret"), tokenClaims(jwt.MapClaims{"aud": "other app"}))
+			},
+			wantErr: true,
+		},
+		{
+			// an expired token of another issuer is invalid and not just expired
+			name: "expired token of another issuer",
+			token: func(t *testing.T) string {
+				return signed(t, jwt.SigningMethodHS256, []byte("secret"), tokenClaims(jwt.MapClaims{"iss": "someone else", "exp": time.Now().Add(-time.Minute).Unix()}))
+			},
+			wantErr: true,
+		},
+		{
+			name: "not valid yet",
+			token: func(t *testing.T) string {
+				return signed(t, jwt.SigningMethodHS256, []byte("secret"), tokenClaims(jwt.MapClaims{"nbf": time.Now().Add(time.Hour).Unix()}))
+			},
+			wantErr: true,
+		},
+		{
+			name: "without expiry",
+			token: func(t *testing.T) string {
+				return signed(t, jwt.SigningMethodHS256, []byte("secret"), tokenClaims(jwt.MapClaims{"exp": nil}))
+			},
+			wantErr: true,
+		},
+		{
+			name:        "legacy token before the deadline",
+			legacyUntil: future,
+			token: func(t *testing.T) string {
+				return signed(t, jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{"sub": "user", "expr": hour, "iat": time.Now().Unix()})
+			},
+			wantLegacy: true,
+		},
+		{
+			name:        "legacy token after the deadline",
+			legacyUntil: past,
+			token: func(t *testing.T) string {
+				return signed(t, jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{"sub": "user", "expr": hour, "iat": time.Now().Unix()})
+			},
+			wantErr: true,
+		},
+		{
+			name: "legacy token without a deadline",
+			token: func(t *testing.T) string {
+				return signed(t, jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{"sub": "user", "expr": hour, "iat": time.Now().Unix()})
+			},
+			wantErr: true,
+		},
+		{
+			name:        "expired legacy token",
+			legacyUntil: future,
+			token: func(t *testing.T) string {
+				return signed(t, jwt.SigningMethodHS256, []byte("secret"), jwt.MapClaims{"sub": "user", "expr": time.Now().Add(-time.Minute).Unix(), "iat": time.Now().Unix()})
+			},
+			wantExpired: true,
+			wantLegacy:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useTokenConfig(t, test.legacyUntil)
+			claims, expired, err := ParseAccessToken(test.token(t))
+			if (err != nil) != test.wantErr || expired != test.wantExpired {
+				t.Fatalf("ParseAccessToken() = %+v, %v, %v, want expired %v and error %v", claims, expired, err, test.wantExpired, test.wantErr)
+			}
+			if err == nil && (claims.UserID != "user" || claims.Legacy != test.wantLegacy) {
+				t.Errorf("ParseAccessToken() = %+v, want legacy %v", claims, test.wantLegacy)
+			}
+		})
+	}
+}